@@ -0,0 +1,184 @@
+package nep413
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+var (
+	// ErrAccessKeyNotFound is returned when a public key is not an access key of the account.
+	ErrAccessKeyNotFound = errors.New("access key does not exist on account")
+	// ErrMissingAccountID is returned when the access key check is enabled, but the response has no account id.
+	ErrMissingAccountID = errors.New("response has no account id")
+	// ErrNotFullAccessKey is returned when RequireFullAccessKey is set and the signing key is a function call key.
+	ErrNotFullAccessKey = errors.New("access key is not a full access key")
+)
+
+// AccessKeyInfo is an access key, as returned by the view_access_key query.
+type AccessKeyInfo struct {
+	// Nonce is the access key's transaction nonce
+	Nonce uint64
+	// BlockHeight is the height of the block the key was read at
+	BlockHeight uint64
+	// BlockHash is the hash of the block the key was read at
+	BlockHash string
+	// FullAccess is true for full access keys, and false for function call keys
+	FullAccess bool
+	// FunctionCall is the permission of a function call key, or nil for a full access key
+	FunctionCall *FunctionCallPermission
+}
+
+// FunctionCallPermission is what a function call access key may do: call the listed methods
+// of a single contract, paying for gas out of its allowance.
+type FunctionCallPermission struct {
+	// ReceiverId is the contract the key may call
+	ReceiverId string
+	// MethodNames are the methods the key may call, or empty for any method
+	MethodNames []string
+	// Allowance is the amount of yoctoNEAR the key may spend on gas, or nil for no limit
+	Allowance *big.Int
+}
+
+// Allows reports whether the permission allows calling method on the receiver contract,
+// e.g. to only authorize keys that may call a specific contract.
+func (p *FunctionCallPermission) Allows(receiverID, method string) bool {
+	if p.ReceiverId != receiverID {
+		return false
+	}
+
+	return len(p.MethodNames) == 0 || slices.Contains(p.MethodNames, method)
+}
+
+// accessKeyPermissionJSON is a function call permission, as returned by NEAR's RPC. The
+// permission of a full access key is the string "FullAccess" instead.
+type accessKeyPermissionJSON struct {
+	FunctionCall *struct {
+		ReceiverId  string   `json:"receiver_id"`
+		MethodNames []string `json:"method_names"`
+		// Allowance is a decimal string of yoctoNEAR, or null for no limit
+		Allowance *string `json:"allowance"`
+	} `json:"FunctionCall"`
+}
+
+// parseAccessKeyPermission parses the permission of an access key, as returned by NEAR's RPC,
+// returning nil for a full access key.
+func parseAccessKeyPermission(raw json.RawMessage) (*FunctionCallPermission, error) {
+	var fullAccess string
+	if err := json.Unmarshal(raw, &fullAccess); err == nil {
+		if fullAccess != "FullAccess" {
+			return nil, fmt.Errorf("unknown access key permission %q", fullAccess)
+		}
+		return nil, nil
+	}
+
+	var permission accessKeyPermissionJSON
+	if err := json.Unmarshal(raw, &permission); err != nil || permission.FunctionCall == nil {
+		return nil, fmt.Errorf("invalid access key permission: %s", raw)
+	}
+
+	functionCall := &FunctionCallPermission{
+		ReceiverId:  permission.FunctionCall.ReceiverId,
+		MethodNames: permission.FunctionCall.MethodNames,
+	}
+	if permission.FunctionCall.Allowance != nil {
+		allowance, ok := new(big.Int).SetString(*permission.FunctionCall.Allowance, 10)
+		if !ok || allowance.Sign() < 0 {
+			return nil, fmt.Errorf("invalid access key allowance %q", *permission.FunctionCall.Allowance)
+		}
+		functionCall.Allowance = allowance
+	}
+
+	return functionCall, nil
+}
+
+// AccessKeyViewer looks up access keys on chain. *RPCClient implements it.
+type AccessKeyViewer interface {
+	// ViewAccessKey returns the access key with the public key on the account, or
+	// ErrAccessKeyNotFound if the key does not belong to the account.
+	ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*AccessKeyInfo, error)
+}
+
+// AccountAccessKey is one of the access keys of an account.
+type AccountAccessKey struct {
+	PublicKey ed25519.PublicKey
+	Info      *AccessKeyInfo
+}
+
+// AccessKeyLister lists the access keys of an account on chain. *RPCClient implements it.
+type AccessKeyLister interface {
+	// ViewAccessKeyList returns the ed25519 access keys of the account.
+	ViewAccessKeyList(ctx context.Context, accountID string) ([]AccountAccessKey, error)
+}
+
+// WithAccessKeyCheck binds the response's account id to the signing key, by looking up the
+// key on the account with viewer (e.g. an *RPCClient). Verification fails with
+// ErrMissingAccountID if the response has no account id, and ErrAccessKeyNotFound if the key
+// is not an access key of the account. The access key is returned in VerifyResult.AccessKey.
+func WithAccessKeyCheck(viewer AccessKeyViewer) VerifyOption {
+	return func(c *verifyConfig) {
+		c.accessKeys = viewer
+	}
+}
+
+// RequireFullAccessKey fails verification with ErrNotFullAccessKey if the signing key is a
+// function call access key, which can only call specific contract methods, rather than a
+// full access key of the account. It only applies with WithAccessKeyCheck.
+func RequireFullAccessKey() VerifyOption {
+	return func(c *verifyConfig) {
+		c.requireFullAccess = true
+	}
+}
+
+// checkMatchedAccessKey checks the access key that matched the signature. Only ed25519 keys
+// can be looked up, so the check fails closed for secp256k1 keys.
+func (c *verifyConfig) checkMatchedAccessKey(ctx context.Context, res *Nep413SignatureResponse, match *signatureMatch) (*AccessKeyInfo, error) {
+	if match.secp256k1Key != nil {
+		if c.accessKeys != nil {
+			return nil, fmt.Errorf("access key checks are not supported for %s keys", KeyTypeSECP256K1)
+		}
+		return nil, nil
+	}
+
+	return c.checkAccessKey(ctx, res, match.key)
+}
+
+// checkAccessKey looks up the signing key on the response's account, if the access key check is enabled.
+func (c *verifyConfig) checkAccessKey(ctx context.Context, res *Nep413SignatureResponse, pub ed25519.PublicKey) (*AccessKeyInfo, error) {
+	if c.accessKeys == nil {
+		return nil, nil
+	}
+
+	if res.AccountId == "" {
+		return nil, ErrMissingAccountID
+	}
+
+	var info *AccessKeyInfo
+	var err error
+	if c.accessKeyCache != nil {
+		// cached keys expire according to the verifier's clock
+		info, err = c.accessKeyCache.view(ctx, normalizeAccountID(res.AccountId), pub, c.now)
+	} else {
+		info, err = c.accessKeys.ViewAccessKey(ctx, normalizeAccountID(res.AccountId), pub)
+	}
+	if err != nil {
+		if errors.Is(err, ErrAccessKeyNotFound) {
+			return nil, err
+		}
+		// a lookup failing because the context is done is not an RPC failure
+		if ctxErr := contextErr(ctx); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("failed to look up access key: %w", err)
+	}
+
+	if c.requireFullAccess && !info.FullAccess {
+		return nil, ErrNotFullAccessKey
+	}
+
+	return info, nil
+}
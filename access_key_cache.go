@@ -0,0 +1,315 @@
+package nep413
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithAccessKeyCache caches the access keys looked up by WithAccessKeyCheck for ttl, so that
+// repeated verifications for an account do not each query the chain. Use
+// Verifier.InvalidateAccount and Verifier.InvalidateKey to evict keys that are known to have
+// changed, without waiting for the ttl. The cache holds up to 10000 keys, unless set otherwise
+// with WithAccessKeyCacheSize.
+// The cache lives in the verifier, so the option only has an effect when passed to
+// NewVerifier: the package-level functions, such as Verify and VerifyJSON, build a new,
+// empty cache for every call.
+func WithAccessKeyCache(ttl time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.accessKeyCacheTTL = ttl
+	}
+}
+
+// defaultAccessKeyCacheSize is the number of keys the access key cache holds by default.
+const defaultAccessKeyCacheSize = 10000
+
+// WithAccessKeyCacheSize bounds the number of keys cached by WithAccessKeyCache and
+// WithNegativeCacheTTL together. Once the cache is full, expired keys are evicted, and then
+// those closest to expiring, so that unknown keys, which an attacker can make up at will,
+// cannot grow the cache without bound. A size of 0 or less uses the default of 10000.
+func WithAccessKeyCacheSize(size int) VerifyOption {
+	return func(c *verifyConfig) {
+		c.accessKeyCacheSize = size
+	}
+}
+
+// InvalidateAccount evicts all cached access keys of the account, e.g. after learning that
+// a key was added or removed. It is a no-op if the verifier has no access key cache.
+// Lookups already in progress are not cached, since they may have read the key before it
+// changed; this holds for InvalidateKey too.
+func (v *Verifier) InvalidateAccount(accountID string) {
+	if cache := v.policy().accessKeyCache; cache != nil {
+		cache.invalidate(normalizeAccountID(accountID), nil)
+	}
+}
+
+// InvalidateKey evicts the cached access key of the account, e.g. after learning that it
+// was removed. It is a no-op if the verifier has no access key cache.
+func (v *Verifier) InvalidateKey(accountID string, pub ed25519.PublicKey) {
+	if cache := v.policy().accessKeyCache; cache != nil {
+		cache.invalidate(normalizeAccountID(accountID), pub)
+	}
+}
+
+// warmConcurrency is the number of accounts WarmAccessKeys lists concurrently.
+const warmConcurrency = 8
+
+// WarmAccessKeys fills the access key cache with all access keys of the accounts, e.g. on
+// startup, so that their first verifications do not wait for the chain. The keys are listed
+// with the access key viewer, which must implement AccessKeyLister (as an *RPCClient does),
+// for up to 8 accounts at a time, and cached for the ttl of WithAccessKeyCache.
+// An account that fails to be listed does not stop the others: the errors of all failed
+// accounts are returned joined, and the keys of the other accounts are cached nonetheless.
+// If ctx is done, the accounts not yet listed fail with the context's error.
+func (v *Verifier) WarmAccessKeys(ctx context.Context, accounts []string) error {
+	cfg := v.config()
+	cache := cfg.accessKeyCache
+	if cache == nil || cache.ttl <= 0 {
+		return errors.New("warming access keys requires WithAccessKeyCache")
+	}
+
+	lister, ok := cache.viewer.(AccessKeyLister)
+	if !ok {
+		return fmt.Errorf("access key viewer %T cannot list access keys", cache.viewer)
+	}
+
+	errs := make([]error, len(accounts))
+	sem := make(chan struct{}, warmConcurrency)
+
+	var wg sync.WaitGroup
+	for i, account := range accounts {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(accounts); j++ {
+				errs[j] = fmt.Errorf("account %s: %w", accounts[j], ctx.Err())
+			}
+			wg.Wait()
+			return errors.Join(errs...)
+		}
+
+		wg.Add(1)
+		go func(i int, account string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			accountID := normalizeAccountID(account)
+			generation := cache.currentGeneration()
+			keys, err := lister.ViewAccessKeyList(ctx, accountID)
+			if err != nil {
+				errs[i] = fmt.Errorf("account %s: %w", account, err)
+				return
+			}
+
+			for _, key := range keys {
+				cache.put(generation, accountID, key.PublicKey, key.Info, cache.ttl, cfg.now())
+			}
+		}(i, account)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// WithNegativeCacheTTL caches the lookups of WithAccessKeyCheck that found the key not to
+// be on the account for ttl, so that repeated attempts with an unknown key do not each query
+// the chain, e.g. during an attack. It is separate from WithAccessKeyCache, and either can be
+// set alone. A newly added key is rejected until the ttl passes, so keep it short, e.g. a few
+// seconds, or evict the key with Verifier.InvalidateKey once it is known to have been added.
+// Only ErrAccessKeyNotFound is cached; RPC errors never are. Like WithAccessKeyCache, it only
+// has an effect on a Verifier.
+func WithNegativeCacheTTL(ttl time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.negativeCacheTTL = ttl
+	}
+}
+
+// accessKeyCache is a concurrency safe AccessKeyViewer that caches the keys of another.
+type accessKeyCache struct {
+	viewer AccessKeyViewer
+	ttl    time.Duration
+	// negativeTTL is how long keys that are not on the account are cached for
+	negativeTTL time.Duration
+	// maxEntries is the number of keys cached before evicting any
+	maxEntries int
+
+	mu sync.Mutex
+	// accounts maps an account id to its cached keys, keyed by the public key bytes
+	accounts map[string]map[string]cachedAccessKey
+	// entries is the number of keys in accounts
+	entries int
+	// generation is incremented by every invalidation, so that lookups that started before
+	// one do not cache what they found afterwards
+	generation uint64
+}
+
+type cachedAccessKey struct {
+	// info is the access key, or nil if the key is not on the account
+	info   *AccessKeyInfo
+	expiry time.Time
+}
+
+var _ AccessKeyViewer = (*accessKeyCache)(nil)
+
+func newAccessKeyCache(viewer AccessKeyViewer, ttl, negativeTTL time.Duration, maxEntries int) *accessKeyCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultAccessKeyCacheSize
+	}
+
+	return &accessKeyCache{
+		viewer:      viewer,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		accounts:    make(map[string]map[string]cachedAccessKey),
+	}
+}
+
+// ViewAccessKey implements AccessKeyViewer, returning the cached key if it has not expired.
+func (c *accessKeyCache) ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*AccessKeyInfo, error) {
+	return c.view(ctx, accountID, pub, time.Now)
+}
+
+// view is ViewAccessKey, with expiry according to the clock now.
+func (c *accessKeyCache) view(ctx context.Context, accountID string, pub ed25519.PublicKey, now func() time.Time) (*AccessKeyInfo, error) {
+	info, generation, ok := c.get(accountID, pub, now())
+	if ok {
+		if info == nil {
+			return nil, ErrAccessKeyNotFound
+		}
+		return info, nil
+	}
+
+	info, err := c.viewer.ViewAccessKey(ctx, accountID, pub)
+	if errors.Is(err, ErrAccessKeyNotFound) && c.negativeTTL > 0 {
+		c.put(generation, accountID, pub, nil, c.negativeTTL, now())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ttl > 0 {
+		c.put(generation, accountID, pub, info, c.ttl, now())
+	}
+	return info, nil
+}
+
+// get returns the cached key, if it has not expired, and the current generation, to pass
+// to put once the key has been looked up.
+func (c *accessKeyCache) get(accountID string, pub ed25519.PublicKey, now time.Time) (*AccessKeyInfo, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.accounts[accountID][string(pub)]
+	if !ok {
+		return nil, c.generation, false
+	}
+	if !now.Before(cached.expiry) {
+		c.remove(accountID, string(pub))
+		return nil, c.generation, false
+	}
+
+	return cached.info, c.generation, true
+}
+
+// put caches a key looked up in the given generation, unless the cache has been invalidated
+// since, in which case the key may have been revoked while it was looked up.
+func (c *accessKeyCache) put(generation uint64, accountID string, pub ed25519.PublicKey, info *AccessKeyInfo, ttl time.Duration, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if _, ok := c.accounts[accountID][string(pub)]; !ok {
+		if c.entries >= c.maxEntries {
+			c.evict(now)
+		}
+		c.entries++
+	}
+
+	keys, ok := c.accounts[accountID]
+	if !ok {
+		keys = make(map[string]cachedAccessKey)
+		c.accounts[accountID] = keys
+	}
+
+	keys[string(pub)] = cachedAccessKey{
+		info:   info,
+		expiry: now.Add(ttl),
+	}
+}
+
+// evict makes room for a key in a full cache, by removing all expired keys, or if none have
+// expired, the key closest to expiring. c.mu must be held.
+func (c *accessKeyCache) evict(now time.Time) {
+	var oldestAccount, oldestKey string
+	var oldest time.Time
+	for accountID, keys := range c.accounts {
+		for key, cached := range keys {
+			if !now.Before(cached.expiry) {
+				c.remove(accountID, key)
+				continue
+			}
+			if oldest.IsZero() || cached.expiry.Before(oldest) {
+				oldestAccount, oldestKey, oldest = accountID, key, cached.expiry
+			}
+		}
+	}
+
+	if c.entries >= c.maxEntries && !oldest.IsZero() {
+		c.remove(oldestAccount, oldestKey)
+	}
+}
+
+// remove deletes a cached key, and its account once it has none left. c.mu must be held.
+func (c *accessKeyCache) remove(accountID, key string) {
+	keys := c.accounts[accountID]
+	if _, ok := keys[key]; !ok {
+		return
+	}
+
+	delete(keys, key)
+	c.entries--
+	if len(keys) == 0 {
+		delete(c.accounts, accountID)
+	}
+}
+
+// currentGeneration returns the generation to pass to put for a lookup starting now.
+func (c *accessKeyCache) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// invalidate evicts the key of the account, or all of its keys if pub is nil.
+func (c *accessKeyCache) invalidate(accountID string, pub ed25519.PublicKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	if pub == nil {
+		c.entries -= len(c.accounts[accountID])
+		delete(c.accounts, accountID)
+		return
+	}
+
+	c.remove(accountID, string(pub))
+}
+
+// flush evicts all cached keys.
+func (c *accessKeyCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+
+	c.accounts = make(map[string]map[string]cachedAccessKey)
+	c.entries = 0
+}
@@ -0,0 +1,301 @@
+package nep413_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+// countingViewer is an AccessKeyViewer that knows every key, and counts its lookups.
+type countingViewer struct {
+	lookups atomic.Int32
+}
+
+func (c *countingViewer) ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*nep413.AccessKeyInfo, error) {
+	c.lookups.Add(1)
+	return &nep413.AccessKeyInfo{FullAccess: true}, nil
+}
+
+func Test_AccessKeyCache(t *testing.T) {
+	viewer := &countingViewer{}
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(time.Hour))
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	verify := func() {
+		if _, err := v.Verify(msg, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	verify()
+	verify()
+	if viewer.lookups.Load() != 1 {
+		t.Fatalf("expected 1 lookup, got %d", viewer.lookups.Load())
+	}
+
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v.InvalidateKey("IDOS.near", pub)
+	verify()
+	if viewer.lookups.Load() != 2 {
+		t.Fatalf("expected 2 lookups, got %d", viewer.lookups.Load())
+	}
+
+	v.InvalidateAccount("idos.near")
+	verify()
+	if viewer.lookups.Load() != 3 {
+		t.Fatalf("expected 3 lookups, got %d", viewer.lookups.Load())
+	}
+}
+
+func Test_VerifierClose(t *testing.T) {
+	viewer := &countingViewer{}
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(time.Hour))
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := v.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the cache was flushed, so the key is looked up again
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+	if viewer.lookups.Load() != 2 {
+		t.Fatalf("expected 2 lookups, got %d", viewer.lookups.Load())
+	}
+
+	if err := nep413.NewVerifier().Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// addedKeyViewer is an AccessKeyViewer that knows every key once added is set, and counts its lookups.
+type addedKeyViewer struct {
+	added   atomic.Bool
+	lookups atomic.Int32
+}
+
+func (a *addedKeyViewer) ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*nep413.AccessKeyInfo, error) {
+	a.lookups.Add(1)
+	if !a.added.Load() {
+		return nil, nep413.ErrAccessKeyNotFound
+	}
+	return &nep413.AccessKeyInfo{FullAccess: true}, nil
+}
+
+func Test_WithNegativeCacheTTL(t *testing.T) {
+	viewer := &addedKeyViewer{}
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithNegativeCacheTTL(time.Hour))
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	for i := 0; i < 3; i++ {
+		if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrAccessKeyNotFound) {
+			t.Fatalf("expected ErrAccessKeyNotFound, got %v", err)
+		}
+	}
+	if viewer.lookups.Load() != 1 {
+		t.Fatalf("expected 1 lookup, got %d", viewer.lookups.Load())
+	}
+
+	// the added key is rejected until it is evicted
+	viewer.added.Store(true)
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrAccessKeyNotFound) {
+		t.Fatalf("expected ErrAccessKeyNotFound, got %v", err)
+	}
+	v.InvalidateAccount("idos.near")
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	// found keys are not cached without WithAccessKeyCache
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+	if viewer.lookups.Load() != 3 {
+		t.Fatalf("expected 3 lookups, got %d", viewer.lookups.Load())
+	}
+}
+
+func Test_AccessKeyCacheSize(t *testing.T) {
+	msg, res := testVector()
+	verifyAs := func(t *testing.T, v *nep413.Verifier, account string) {
+		t.Helper()
+		withAccount := *res
+		withAccount.AccountId = account
+		if _, err := v.Verify(msg, &withAccount); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("evicts the oldest key once full", func(t *testing.T) {
+		viewer := &countingViewer{}
+		v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(time.Hour), nep413.WithAccessKeyCacheSize(1))
+
+		verifyAs(t, v, "a.near")
+		verifyAs(t, v, "a.near")
+		verifyAs(t, v, "b.near")
+		verifyAs(t, v, "a.near")
+		if viewer.lookups.Load() != 3 {
+			t.Fatalf("expected 3 lookups, got %d", viewer.lookups.Load())
+		}
+	})
+
+	t.Run("evicts expired keys first", func(t *testing.T) {
+		viewer := &countingViewer{}
+		v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(200*time.Millisecond), nep413.WithAccessKeyCacheSize(2))
+
+		verifyAs(t, v, "a.near")
+		verifyAs(t, v, "b.near")
+		time.Sleep(250 * time.Millisecond)
+
+		// a's expired key is replaced on lookup, and b's is evicted to make room for c's
+		verifyAs(t, v, "a.near")
+		verifyAs(t, v, "c.near")
+		verifyAs(t, v, "a.near")
+		verifyAs(t, v, "c.near")
+		if viewer.lookups.Load() != 4 {
+			t.Fatalf("expected 4 lookups, got %d", viewer.lookups.Load())
+		}
+	})
+}
+
+func Test_AccessKeyCacheClock(t *testing.T) {
+	now := time.Now()
+	viewer := &countingViewer{}
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(time.Minute))
+	v.NowFunc = func() time.Time { return now }
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	verify := func() {
+		t.Helper()
+		if _, err := v.Verify(msg, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	verify()
+	now = now.Add(59 * time.Second)
+	verify()
+	if viewer.lookups.Load() != 1 {
+		t.Fatalf("expected 1 lookup, got %d", viewer.lookups.Load())
+	}
+
+	// the key expires by the verifier's clock, not the wall clock
+	now = now.Add(time.Second)
+	verify()
+	if viewer.lookups.Load() != 2 {
+		t.Fatalf("expected 2 lookups, got %d", viewer.lookups.Load())
+	}
+}
+
+// blockingViewer is a countingViewer whose lookups wait for release once started is signalled.
+type blockingViewer struct {
+	countingViewer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingViewer) ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*nep413.AccessKeyInfo, error) {
+	if b.lookups.Load() == 0 {
+		b.started <- struct{}{}
+		<-b.release
+	}
+	return b.countingViewer.ViewAccessKey(ctx, accountID, pub)
+}
+
+func Test_AccessKeyCacheInvalidateDuringLookup(t *testing.T) {
+	viewer := &blockingViewer{started: make(chan struct{}), release: make(chan struct{})}
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(time.Hour))
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	done := make(chan error)
+	go func() {
+		_, err := v.Verify(msg, res)
+		done <- err
+	}()
+
+	// the key is revoked while it is being looked up
+	<-viewer.started
+	v.InvalidateAccount("idos.near")
+	close(viewer.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	// so what the lookup found was not cached
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+	if viewer.lookups.Load() != 2 {
+		t.Fatalf("expected 2 lookups, got %d", viewer.lookups.Load())
+	}
+}
+
+// listingViewer is a countingViewer that also lists the keys of accounts, failing for
+// accounts it does not know.
+type listingViewer struct {
+	countingViewer
+	keys map[string][]nep413.AccountAccessKey
+}
+
+func (l *listingViewer) ViewAccessKeyList(ctx context.Context, accountID string) ([]nep413.AccountAccessKey, error) {
+	keys, ok := l.keys[accountID]
+	if !ok {
+		return nil, errors.New("unknown account")
+	}
+	return keys, nil
+}
+
+func Test_WarmAccessKeys(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viewer := &listingViewer{keys: map[string][]nep413.AccountAccessKey{
+		"idos.near": {{PublicKey: pub, Info: &nep413.AccessKeyInfo{FullAccess: true, Nonce: 7}}},
+	}}
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer), nep413.WithAccessKeyCache(time.Hour))
+
+	err = v.WarmAccessKeys(context.Background(), []string{"IDOS.near", "unknown.near"})
+	if err == nil || !strings.Contains(err.Error(), "unknown.near") || strings.Contains(err.Error(), "IDOS.near") {
+		t.Fatalf("expected only unknown.near to fail, got %v", err)
+	}
+
+	result, err := v.Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if viewer.lookups.Load() != 0 || result.AccessKey.Nonce != 7 {
+		t.Fatalf("expected the warmed key to be used, got %d lookups and nonce %d", viewer.lookups.Load(), result.AccessKey.Nonce)
+	}
+
+	uncached := nep413.NewVerifier(nep413.WithAccessKeyCheck(viewer))
+	if err := uncached.WarmAccessKeys(context.Background(), []string{"idos.near"}); err == nil {
+		t.Fatal("expected warming without a cache to fail")
+	}
+}
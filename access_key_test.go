@@ -0,0 +1,68 @@
+//go:build !(js && wasm)
+
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_WithAccessKeyCheck(t *testing.T) {
+	msg, res := testVector()
+	server := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {res.PublicKey: `"FullAccess"`},
+	})
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(nep413.NewRPCClient(server.URL)))
+
+	res.AccountId = "idos.near"
+	result, err := v.Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.AccessKey == nil || result.AccessKey.Nonce != 42 || result.AccessKey.BlockHeight != 1000 || !result.AccessKey.FullAccess {
+		t.Fatalf("unexpected access key %+v", result.AccessKey)
+	}
+
+	res.AccountId = "attacker.near"
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrAccessKeyNotFound) {
+		t.Fatalf("expected ErrAccessKeyNotFound, got %v", err)
+	}
+
+	res.AccountId = ""
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrMissingAccountID) {
+		t.Fatalf("expected ErrMissingAccountID, got %v", err)
+	}
+}
+
+func Test_AccessKeyCheckCancelled(t *testing.T) {
+	// the node does not answer before the client gives up
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(nep413.NewRPCClient(server.URL)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	_, err := v.VerifyContext(ctx, msg, res)
+	if !errors.Is(err, nep413.ErrVerificationCancelled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrVerificationCancelled wrapping context.DeadlineExceeded, got %v", err)
+	}
+
+	if reason := nep413.FailureReason(err); reason != "cancelled" {
+		t.Fatalf("expected reason cancelled, got %s", reason)
+	}
+}
@@ -0,0 +1,365 @@
+package nep413
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrAccountNotAllowed is returned when the response's account id is not in the allowlist.
+var ErrAccountNotAllowed = errors.New("account is not allowed")
+
+// ErrAccountNotBound is returned by VerifyWithAllowedAccounts when nothing ties the signing
+// key to the response's account id.
+var ErrAccountNotBound = errors.New("account id is not bound to the signing key")
+
+// Account id length bounds.
+// https://nomicon.io/DataStructures/Account#account-id-rules
+const (
+	minAccountIDLength = 2
+	maxAccountIDLength = 64
+)
+
+// AccountIDErrorReason is why an account id is invalid.
+type AccountIDErrorReason string
+
+// The reasons an account id can be invalid.
+const (
+	AccountIDTooShort              AccountIDErrorReason = "too short"
+	AccountIDTooLong               AccountIDErrorReason = "too long"
+	AccountIDInvalidCharacter      AccountIDErrorReason = "invalid character"
+	AccountIDEmptyLabel            AccountIDErrorReason = "empty label"
+	AccountIDLeadingSeparator      AccountIDErrorReason = "label starts with a separator"
+	AccountIDTrailingSeparator     AccountIDErrorReason = "label ends with a separator"
+	AccountIDConsecutiveSeparators AccountIDErrorReason = "consecutive separators"
+)
+
+// ErrInvalidAccountID is wrapped by every error returned by ValidateAccountID.
+var ErrInvalidAccountID = errors.New("invalid account id")
+
+// AccountIDError describes why an account id is invalid.
+// It wraps ErrInvalidAccountID.
+type AccountIDError struct {
+	// AccountID is the invalid account id
+	AccountID string
+	// Reason is the rule the account id breaks
+	Reason AccountIDErrorReason
+	// Position is the byte offset of the offending character, or of the start of the
+	// offending label. It is -1 for length errors.
+	Position int
+	// Label is the "." separated label containing the error, if any
+	Label string
+}
+
+func (e *AccountIDError) Error() string {
+	if e.Position < 0 {
+		return fmt.Sprintf("%s %q: %s (%d bytes, expected %d to %d)", ErrInvalidAccountID, e.AccountID, e.Reason,
+			len(e.AccountID), minAccountIDLength, maxAccountIDLength)
+	}
+
+	return fmt.Sprintf("%s %q: %s at position %d, in label %q", ErrInvalidAccountID, e.AccountID, e.Reason, e.Position, e.Label)
+}
+
+func (e *AccountIDError) Unwrap() error {
+	return ErrInvalidAccountID
+}
+
+// ValidateAccountID checks that s is a valid NEAR account id: 2 to 64 characters, forming
+// "." separated labels of lowercase alphanumeric parts, which are separated by single "-"
+// or "_". If it is not, the returned *AccountIDError says which rule is broken, and where.
+// https://nomicon.io/DataStructures/Account#account-id-rules
+func ValidateAccountID(s string) error {
+	if len(s) < minAccountIDLength {
+		return &AccountIDError{AccountID: s, Reason: AccountIDTooShort, Position: -1}
+	}
+	if len(s) > maxAccountIDLength {
+		return &AccountIDError{AccountID: s, Reason: AccountIDTooLong, Position: -1}
+	}
+
+	labelStart := 0
+	for labelStart <= len(s) {
+		labelEnd := strings.IndexByte(s[labelStart:], '.')
+		if labelEnd < 0 {
+			labelEnd = len(s)
+		} else {
+			labelEnd += labelStart
+		}
+
+		if err := validateAccountLabel(s, labelStart, labelEnd); err != nil {
+			return err
+		}
+
+		labelStart = labelEnd + 1
+	}
+
+	return nil
+}
+
+// validateAccountLabel validates the label s[start:end] of an account id.
+func validateAccountLabel(s string, start, end int) error {
+	label := s[start:end]
+	fail := func(reason AccountIDErrorReason, pos int) error {
+		return &AccountIDError{AccountID: s, Reason: reason, Position: pos, Label: label}
+	}
+
+	if label == "" {
+		return fail(AccountIDEmptyLabel, start)
+	}
+
+	for i := 0; i < len(label); i++ {
+		switch c := label[i]; {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' || c == '_':
+			if i == 0 {
+				return fail(AccountIDLeadingSeparator, start)
+			}
+			if i == len(label)-1 {
+				return fail(AccountIDTrailingSeparator, start+i)
+			}
+			if next := label[i+1]; next == '-' || next == '_' {
+				return fail(AccountIDConsecutiveSeparators, start+i+1)
+			}
+		default:
+			return fail(AccountIDInvalidCharacter, start+i)
+		}
+	}
+
+	return nil
+}
+
+// IsValidAccountID reports whether s is a valid NEAR account id. See ValidateAccountID
+// for why an id is invalid.
+func IsValidAccountID(s string) bool {
+	return ValidateAccountID(s) == nil
+}
+
+// normalizeAccountID returns the canonical form of a NEAR account id.
+// NEAR account ids are lowercase, so ids differing only by case or
+// surrounding whitespace are the same account.
+func normalizeAccountID(accountID string) string {
+	return strings.ToLower(strings.TrimSpace(accountID))
+}
+
+// EqualAccountID reports whether a and b are the same account. The ids are normalized,
+// and then compared in constant time (for ids of equal length), so the comparison does
+// not leak how much of an id matched.
+func EqualAccountID(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(normalizeAccountID(a)), []byte(normalizeAccountID(b))) == 1
+}
+
+// accountDigest returns the key an account id is stored under in an allowlist. Looking up
+// the sha256 of an id, rather than the id itself, means the timing of the map lookup
+// reveals nothing about which allowed ids share a prefix with the looked up one.
+func accountDigest(accountID string) [32]byte {
+	return sha256.Sum256([]byte(normalizeAccountID(accountID)))
+}
+
+// WithAllowedAccounts fails verification with ErrAccountNotAllowed unless the
+// response's account id is one of the given accounts. Ids are compared in normalized form.
+// The allowlist alone authenticates nothing: the account id is not covered by the signature,
+// so anyone can sign with their own key and claim an allowed account. Combine it with
+// WithAccessKeyCheck, or with DeriveImplicitAccountID for responses without an account id,
+// so that the signing key is tied to the account.
+// To allow patterns of accounts, use WithAccountMatcher; whichever is given last applies.
+func WithAllowedAccounts(accounts ...string) VerifyOption {
+	matcher := AccountMatcher{exact: make(map[[32]byte]struct{}, len(accounts)), patterns: accounts}
+	for _, account := range accounts {
+		matcher.exact[accountDigest(account)] = struct{}{}
+	}
+
+	return WithAccountMatcher(matcher)
+}
+
+// WithAccountMatcher fails verification with ErrAccountNotAllowed unless the response's
+// account id matches m (see CompileAccountMatcher). Like WithAllowedAccounts, which it replaces,
+// it should be combined with a check that the public key belongs to the account.
+func WithAccountMatcher(m AccountMatcher) VerifyOption {
+	return func(c *verifyConfig) {
+		c.allowedAccounts = &m
+	}
+}
+
+// ImplicitAccountsPattern is the CompileAccountMatcher pattern matching every implicit
+// account (see IsImplicitAccount).
+const ImplicitAccountsPattern = "<implicit>"
+
+// ErrInvalidAccountPattern is returned by CompileAccountMatcher for an invalid pattern.
+var ErrInvalidAccountPattern = errors.New("invalid account pattern")
+
+// AccountMatcher matches account ids against a compiled set of patterns.
+// The zero value matches nothing.
+type AccountMatcher struct {
+	// exact is the set of digests of exactly matched account ids
+	exact map[[32]byte]struct{}
+	// parents are the accounts whose subaccounts match
+	parents []string
+	// implicit matches implicit accounts
+	implicit bool
+	// patterns are the patterns the matcher was compiled from
+	patterns []string
+}
+
+// CompileAccountMatcher compiles allowlist patterns into an AccountMatcher. Each pattern is:
+//   - an account id, e.g. "idos.near", matching only that account
+//   - "*." followed by an account id, e.g. "*.myapp.near", matching its subaccounts at any
+//     depth (which only myapp.near can create), but not myapp.near itself
+//   - ImplicitAccountsPattern, matching every implicit account
+//
+// Patterns are normalized like account ids. "*" is only allowed as the first label, so that
+// no pattern matches across account boundaries; any other pattern, including a lone "*",
+// fails with ErrInvalidAccountPattern.
+func CompileAccountMatcher(patterns []string) (AccountMatcher, error) {
+	m := AccountMatcher{exact: make(map[[32]byte]struct{}), patterns: patterns}
+
+	for _, pattern := range patterns {
+		normalized := normalizeAccountID(pattern)
+
+		if normalized == ImplicitAccountsPattern {
+			m.implicit = true
+			continue
+		}
+
+		parent, wildcard := strings.CutPrefix(normalized, "*.")
+		if err := ValidateAccountID(parent); err != nil {
+			return AccountMatcher{}, fmt.Errorf("%w %q: %w", ErrInvalidAccountPattern, pattern, err)
+		}
+
+		if wildcard {
+			m.parents = append(m.parents, parent)
+		} else {
+			m.exact[accountDigest(parent)] = struct{}{}
+		}
+	}
+
+	return m, nil
+}
+
+// Match reports whether the account id matches any of the matcher's patterns.
+// The id is normalized first, and must be a valid account id to match a wildcard.
+func (m AccountMatcher) Match(accountID string) bool {
+	if _, ok := m.exact[accountDigest(accountID)]; ok {
+		return true
+	}
+
+	if !m.implicit && len(m.parents) == 0 {
+		return false
+	}
+
+	normalized := normalizeAccountID(accountID)
+	if !IsValidAccountID(normalized) {
+		return false
+	}
+
+	if m.implicit && IsImplicitAccount(normalized) {
+		return true
+	}
+
+	for _, parent := range m.parents {
+		if strings.HasSuffix(normalized, "."+parent) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ImplicitAccountID returns the implicit account id of a public key: its hex encoding.
+func ImplicitAccountID(pub ed25519.PublicKey) string {
+	return hex.EncodeToString(pub)
+}
+
+// DeriveImplicitAccountID treats a response without an account id as signed by the implicit
+// account of its signing key, which wallets of implicit accounts may omit. The derived id is
+// used by the account allowlist and the access key check, and returned in
+// VerifyResult.AccountId, with VerifyResult.AccountIdDerived set. Named accounts cannot be
+// derived from a key, so responses with an account id are unaffected.
+func DeriveImplicitAccountID() VerifyOption {
+	return func(c *verifyConfig) {
+		c.deriveImplicitAccount = true
+	}
+}
+
+// deriveAccount returns the response with its account id set to the matched key's implicit
+// account, if enabled and the response has none, and whether it was derived.
+// The caller's response is not modified.
+func (c *verifyConfig) deriveAccount(res *Nep413SignatureResponse, matched ed25519.PublicKey) (*Nep413SignatureResponse, bool) {
+	if !c.deriveImplicitAccount || res.AccountId != "" {
+		return res, false
+	}
+
+	withAccount := *res
+	withAccount.AccountId = ImplicitAccountID(matched)
+	return &withAccount, true
+}
+
+// IsImplicitAccount reports whether an account id is an implicit account: the hex encoding
+// of an ed25519 public key, i.e. 64 lowercase hex characters.
+func IsImplicitAccount(id string) bool {
+	if len(id) != hex.EncodedLen(ed25519.PublicKeySize) {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AccountKind is the kind of a NEAR account id.
+type AccountKind int
+
+const (
+	// AccountKindInvalid is an id that is not a valid account id
+	AccountKindInvalid AccountKind = iota
+	// AccountKindImplicit is an implicit account, whose id is the hex encoding of its key
+	AccountKindImplicit
+	// AccountKindNamed is a named account, e.g. "idos.near"
+	AccountKindNamed
+)
+
+func (k AccountKind) String() string {
+	switch k {
+	case AccountKindImplicit:
+		return "implicit"
+	case AccountKindNamed:
+		return "named"
+	default:
+		return "invalid"
+	}
+}
+
+// AccountKindOf returns the kind of an account id. The id is not normalized first, so
+// ids with uppercase characters are invalid.
+func AccountKindOf(id string) AccountKind {
+	switch {
+	case IsImplicitAccount(id):
+		return AccountKindImplicit
+	case IsValidAccountID(id):
+		return AccountKindNamed
+	default:
+		return AccountKindInvalid
+	}
+}
+
+// VerifyWithAllowedAccounts verifies an NEP-413 signature, and then checks that the
+// response's account id is one of the given accounts.
+// The allowlist alone authenticates nothing, since the account id is not signed, so opts must
+// tie the signing key to the account: either WithAccessKeyCheck, or DeriveImplicitAccountID
+// for a response without an account id. Otherwise it fails with ErrAccountNotBound.
+func VerifyWithAllowedAccounts(msg *Nep413Message, res *Nep413SignatureResponse, accounts []string, opts ...VerifyOption) error {
+	cfg := newVerifyConfig(append(opts, WithAllowedAccounts(accounts...)))
+	if cfg.accessKeys == nil && (!cfg.deriveImplicitAccount || res.AccountId != "") {
+		return ErrAccountNotBound
+	}
+
+	_, err := verifyResponseKey(context.Background(), cfg, msg, res)
+	return err
+}
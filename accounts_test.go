@@ -0,0 +1,238 @@
+package nep413_test
+
+import (
+	"errors"
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifyWithAllowedAccounts(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "Admin.NEAR"
+	keyCheck := nep413.WithAccessKeyCheck(&countingViewer{})
+
+	err := nep413.VerifyWithAllowedAccounts(msg, res, []string{"other.near", "admin.near"}, keyCheck)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = nep413.VerifyWithAllowedAccounts(msg, res, []string{"other.near"}, keyCheck)
+	if !errors.Is(err, nep413.ErrAccountNotAllowed) {
+		t.Fatalf("expected ErrAccountNotAllowed, got %v", err)
+	}
+
+	// the claimed account id is not signed, so it is not trusted without a binding to the key
+	err = nep413.VerifyWithAllowedAccounts(msg, res, []string{"admin.near"})
+	if !errors.Is(err, nep413.ErrAccountNotBound) {
+		t.Fatalf("expected ErrAccountNotBound, got %v", err)
+	}
+	err = nep413.VerifyWithAllowedAccounts(msg, res, []string{"admin.near"}, nep413.DeriveImplicitAccountID())
+	if !errors.Is(err, nep413.ErrAccountNotBound) {
+		t.Fatalf("expected ErrAccountNotBound for a claimed account id, got %v", err)
+	}
+
+	// without an account id, the implicit account is derived from the signing key
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	implicit := *res
+	implicit.AccountId = ""
+	err = nep413.VerifyWithAllowedAccounts(msg, &implicit, []string{nep413.ImplicitAccountID(pub)}, nep413.DeriveImplicitAccountID())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_EqualAccountID(t *testing.T) {
+	if !nep413.EqualAccountID("Idos.near", " idos.near") {
+		t.Fatal("expected normalized account ids to be equal")
+	}
+
+	if nep413.EqualAccountID("idos.near", "idos.nea") || nep413.EqualAccountID("idos.near", "ibos.near") {
+		t.Fatal("expected different account ids to not be equal")
+	}
+}
+
+func Test_ValidateAccountID(t *testing.T) {
+	valid := []string{"ab", "idos.near", "a-b_c.near", "0x1.testnet", strings.Repeat("a", 64)}
+	for _, id := range valid {
+		if err := nep413.ValidateAccountID(id); err != nil {
+			t.Fatalf("expected %s to be valid, got %v", id, err)
+		}
+		if !nep413.IsValidAccountID(id) {
+			t.Fatalf("expected %s to be valid", id)
+		}
+	}
+
+	invalid := []struct {
+		id       string
+		reason   nep413.AccountIDErrorReason
+		position int
+		label    string
+	}{
+		{"a", nep413.AccountIDTooShort, -1, ""},
+		{strings.Repeat("a", 65), nep413.AccountIDTooLong, -1, ""},
+		{"idos.Near", nep413.AccountIDInvalidCharacter, 5, "Near"},
+		{"idos..near", nep413.AccountIDEmptyLabel, 5, ""},
+		{".near", nep413.AccountIDEmptyLabel, 0, ""},
+		{"idos.", nep413.AccountIDEmptyLabel, 5, ""},
+		{"idos.-near", nep413.AccountIDLeadingSeparator, 5, "-near"},
+		{"idos_.near", nep413.AccountIDTrailingSeparator, 4, "idos_"},
+		{"id-_os.near", nep413.AccountIDConsecutiveSeparators, 3, "id-_os"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.id, func(t *testing.T) {
+			err := nep413.ValidateAccountID(tc.id)
+			if !errors.Is(err, nep413.ErrInvalidAccountID) {
+				t.Fatalf("expected ErrInvalidAccountID, got %v", err)
+			}
+
+			var idErr *nep413.AccountIDError
+			if !errors.As(err, &idErr) {
+				t.Fatalf("expected *AccountIDError, got %T", err)
+			}
+			if idErr.Reason != tc.reason || idErr.Position != tc.position || idErr.Label != tc.label {
+				t.Fatalf("expected %s at %d in %q, got %s at %d in %q", tc.reason, tc.position, tc.label, idErr.Reason, idErr.Position, idErr.Label)
+			}
+
+			if nep413.IsValidAccountID(tc.id) {
+				t.Fatalf("expected %s to be invalid", tc.id)
+			}
+		})
+	}
+}
+
+// Test_ValidateAccountIDMatchesRules checks ValidateAccountID against the account id
+// regular expression from the NEAR spec, on random ids.
+func Test_ValidateAccountIDMatchesRules(t *testing.T) {
+	pattern := regexp.MustCompile(`^(([a-z\d]+[\-_])*[a-z\d]+\.)*([a-z\d]+[\-_])*[a-z\d]+$`)
+	alphabet := "ab0-_.A"
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 20000; i++ {
+		b := make([]byte, 2+rng.Intn(10))
+		for j := range b {
+			b[j] = alphabet[rng.Intn(len(alphabet))]
+		}
+		id := string(b)
+
+		if expected := pattern.MatchString(id); nep413.IsValidAccountID(id) != expected {
+			t.Fatalf("%q: expected valid = %v", id, expected)
+		}
+	}
+}
+
+func Test_CompileAccountMatcher(t *testing.T) {
+	matcher, err := nep413.CompileAccountMatcher([]string{"idos.near", "*.MyApp.near", nep413.ImplicitAccountsPattern})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]bool{
+		"idos.near":              true,
+		"IDOS.near":              true,
+		"sub.idos.near":          false,
+		"alice.myapp.near":       true,
+		"a.b.myapp.near":         true,
+		"myapp.near":             false,
+		"evilmyapp.near":         false,
+		"evil..myapp.near":       false,
+		"myapp.near.evil":        false,
+		strings.Repeat("a0", 32): true,
+		strings.Repeat("g0", 32): false,
+		strings.Repeat("a0", 31): false,
+	}
+	for id, expected := range cases {
+		if matcher.Match(id) != expected {
+			t.Fatalf("%s: expected match = %v", id, expected)
+		}
+	}
+
+	for _, pattern := range []string{"*", "*.near.*", "my*.near", "**.near", "*.", ""} {
+		if _, err := nep413.CompileAccountMatcher([]string{pattern}); !errors.Is(err, nep413.ErrInvalidAccountPattern) {
+			t.Fatalf("%q: expected ErrInvalidAccountPattern, got %v", pattern, err)
+		}
+	}
+
+	msg, res := testVector()
+	res.AccountId = "alice.myapp.near"
+	if err := nep413.Verify(msg, res, nep413.WithAccountMatcher(matcher)); err != nil {
+		t.Fatal(err)
+	}
+
+	res.AccountId = "myapp.near"
+	if err := nep413.Verify(msg, res, nep413.WithAccountMatcher(matcher)); !errors.Is(err, nep413.ErrAccountNotAllowed) {
+		t.Fatalf("expected ErrAccountNotAllowed, got %v", err)
+	}
+}
+
+func Test_DeriveImplicitAccountID(t *testing.T) {
+	msg, res := testVector()
+
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	implicit := nep413.ImplicitAccountID(pub)
+	if len(implicit) != 64 {
+		t.Fatalf("expected 64 hex characters, got %s", implicit)
+	}
+
+	v := nep413.NewVerifier(nep413.DeriveImplicitAccountID(), nep413.WithAllowedAccounts(implicit, "idos.near"))
+
+	result, err := v.Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccountId != implicit || !result.AccountIdDerived {
+		t.Fatalf("expected derived account id %s, got %s", implicit, result.AccountId)
+	}
+	if res.AccountId != "" {
+		t.Fatal("expected the response to be unchanged")
+	}
+
+	// named accounts are not derived
+	res.AccountId = "idos.near"
+	result, err = v.Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccountId != "idos.near" || result.AccountIdDerived {
+		t.Fatalf("expected claimed account id idos.near, got %s", result.AccountId)
+	}
+
+	// without the option, the account id is left empty
+	res.AccountId = ""
+	result, err = nep413.NewVerifier().Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccountId != "" || result.AccountIdDerived {
+		t.Fatalf("expected no account id, got %s", result.AccountId)
+	}
+}
+
+func Test_AccountKindOf(t *testing.T) {
+	cases := map[string]nep413.AccountKind{
+		strings.Repeat("ab", 32): nep413.AccountKindImplicit,
+		strings.Repeat("AB", 32): nep413.AccountKindInvalid,
+		strings.Repeat("ab", 31): nep413.AccountKindNamed,
+		"idos.near":              nep413.AccountKindNamed,
+		"Idos.near":              nep413.AccountKindInvalid,
+		"":                       nep413.AccountKindInvalid,
+	}
+
+	for id, expected := range cases {
+		if kind := nep413.AccountKindOf(id); kind != expected {
+			t.Fatalf("%q: expected %s, got %s", id, expected, kind)
+		}
+		if nep413.IsImplicitAccount(id) != (expected == nep413.AccountKindImplicit) {
+			t.Fatalf("%q: unexpected IsImplicitAccount", id)
+		}
+	}
+}
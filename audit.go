@@ -0,0 +1,57 @@
+package nep413
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+// auditRecordVersion is the version of the AuditRecord format. It is bumped whenever a
+// field is changed or removed, so that old log lines can still be told apart.
+const auditRecordVersion = 1
+
+// auditRecord is the JSON form of an AuditRecord.
+type auditRecord struct {
+	Version        int    `json:"version"`
+	AccountId      string `json:"accountId"`
+	KeyFingerprint string `json:"keyFingerprint"`
+	Recipient      string `json:"recipient"`
+	Nonce          string `json:"nonce"`
+	PayloadHash    string `json:"payloadHash"`
+	Timestamp      string `json:"timestamp"`
+}
+
+// AuditRecord returns a compact record of the verification for an append-only audit log:
+// a single line of canonical JSON (see CanonicalJSON), terminated by a newline, of the form
+//
+//	{"accountId":...,"keyFingerprint":...,"nonce":...,"payloadHash":...,"recipient":...,"timestamp":...,"version":1}
+//
+// The nonce is base58 encoded, the payload hash hex encoded, and the timestamp is VerifiedAt
+// in RFC 3339 format, in UTC. The key is recorded by its KeyFingerprint. The version is
+// incremented whenever the format changes.
+func (r *VerifyResult) AuditRecord() ([]byte, error) {
+	line, err := CanonicalJSON(auditRecord{
+		Version:        auditRecordVersion,
+		AccountId:      r.AccountId,
+		KeyFingerprint: KeyFingerprint(r.keyBytes()),
+		Recipient:      r.Recipient,
+		Nonce:          base58.Encode(r.Nonce[:]),
+		PayloadHash:    hex.EncodeToString(r.PayloadHash[:]),
+		Timestamp:      r.VerifiedAt.UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+// keyBytes returns the bytes of the key that validated the signature, of either type.
+func (r *VerifyResult) keyBytes() []byte {
+	if r.Secp256k1PublicKey != nil {
+		return r.Secp256k1PublicKey.SerializeUncompressed()[1:]
+	}
+
+	return r.PublicKey
+}
@@ -0,0 +1,38 @@
+package nep413_test
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+)
+
+func Test_AuditRecord(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	v := nep413.NewVerifier()
+	v.NowFunc = func() time.Time { return time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600)) }
+
+	result, err := v.Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record, err := result.AuditRecord()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the format is stable, so the record is compared exactly
+	expected := `{"accountId":"idos.near","keyFingerprint":"` + nep413.KeyFingerprint(result.PublicKey) +
+		`","nonce":"` + base58.Encode(msg.Nonce[:]) +
+		`","payloadHash":"` + hex.EncodeToString(result.PayloadHash[:]) +
+		`","recipient":"idos.network","timestamp":"2024-01-01T11:00:00Z","version":1}` + "\n"
+
+	if string(record) != expected {
+		t.Fatalf("expected %s, got %s", expected, record)
+	}
+}
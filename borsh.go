@@ -0,0 +1,130 @@
+package nep413
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrMalformedInput is returned when binary input cannot be decoded, e.g. because it is
+// truncated, too large, or has a length prefix pointing past its end.
+var ErrMalformedInput = errors.New("malformed input")
+
+// maxBinaryInputSize caps the size of borsch input decoded from untrusted sources.
+// Real responses and messages are a few hundred bytes.
+const maxBinaryInputSize = 64 << 10
+
+// borschDecoder decodes borsch values from a byte slice, bounds checking every read,
+// so that a malicious length prefix fails instead of causing a large allocation.
+type borschDecoder struct {
+	data []byte
+}
+
+// newBorschDecoder returns a decoder for data, rejecting input above maxBinaryInputSize.
+func newBorschDecoder(data []byte) (*borschDecoder, error) {
+	if len(data) > maxBinaryInputSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the limit of %d", ErrMalformedInput, len(data), maxBinaryInputSize)
+	}
+
+	return &borschDecoder{data: data}, nil
+}
+
+// bytes reads the next n bytes.
+func (d *borschDecoder) bytes(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data) {
+		return nil, fmt.Errorf("%w: length %d exceeds the %d remaining bytes", ErrMalformedInput, n, len(d.data))
+	}
+
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *borschDecoder) u32() (uint32, error) {
+	b, err := d.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (d *borschDecoder) u8() (uint8, error) {
+	b, err := d.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+
+	return b[0], nil
+}
+
+// string reads a u32 length prefixed string.
+func (d *borschDecoder) string() (string, error) {
+	n, err := d.u32()
+	if err != nil {
+		return "", err
+	}
+
+	// the length is checked against the remaining input before anything is allocated
+	if uint64(n) > uint64(len(d.data)) {
+		return "", fmt.Errorf("%w: string length %d exceeds the %d remaining bytes", ErrMalformedInput, n, len(d.data))
+	}
+
+	b, err := d.bytes(int(n))
+	return string(b), err
+}
+
+// optionalString reads an Option<String>: a 0 byte for None, or a 1 byte followed by the string.
+func (d *borschDecoder) optionalString() (*string, error) {
+	some, err := d.u8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch some {
+	case 0:
+		return nil, nil
+	case 1:
+		s, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return &s, nil
+	default:
+		return nil, fmt.Errorf("%w: invalid option tag %d", ErrMalformedInput, some)
+	}
+}
+
+// finish fails if any input is left over.
+func (d *borschDecoder) finish() error {
+	if len(d.data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes", ErrMalformedInput, len(d.data))
+	}
+
+	return nil
+}
+
+// message decodes a message into msg, field by field, so that the fields before an error are set.
+func (d *borschDecoder) message(msg *Nep413Message) (err error) {
+	if msg.Tag, err = d.u32(); err != nil {
+		return err
+	}
+	if msg.Message, err = d.string(); err != nil {
+		return err
+	}
+
+	nonce, err := d.bytes(len(msg.Nonce))
+	if err != nil {
+		return err
+	}
+	copy(msg.Nonce[:], nonce)
+
+	if msg.Recipient, err = d.string(); err != nil {
+		return err
+	}
+	if msg.CallbackUrl, err = d.optionalString(); err != nil {
+		return err
+	}
+
+	return nil
+}
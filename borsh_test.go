@@ -0,0 +1,317 @@
+package nep413_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_UnmarshalBinaryMalformed(t *testing.T) {
+	_, res := testVector()
+	res.AccountId = "idos.near"
+
+	data, err := res.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a length prefix claiming ~4GB
+	huge := binary.LittleEndian.AppendUint32(nil, 0xffffffff)
+
+	cases := map[string][]byte{
+		"empty":          {},
+		"truncated":      data[:len(data)-1],
+		"trailing bytes": append(append([]byte{}, data...), 0),
+		"huge length":    huge,
+		"oversized":      make([]byte, 1<<20),
+	}
+
+	for name, input := range cases {
+		t.Run(name, func(t *testing.T) {
+			var decoded nep413.Nep413SignatureResponse
+			if err := decoded.UnmarshalBinary(input); !errors.Is(err, nep413.ErrMalformedInput) {
+				t.Fatalf("expected ErrMalformedInput, got %v", err)
+			}
+		})
+	}
+
+	var decoded nep413.Nep413SignatureResponse
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != *res {
+		t.Fatalf("expected %+v, got %+v", *res, decoded)
+	}
+}
+
+// Test_ParseBaselineResponse decodes a response written by the first release of the package,
+// whose borsch form only had the signature and public key.
+func Test_ParseBaselineResponse(t *testing.T) {
+	_, res := testVector()
+	baseline, err := hex.DecodeString("580000004e692b7258764f74797a527237582b71747651392b694a55753265384c2f653663506a537a4f59722b365732326368566e7074545730517154556846674b556267507764327454636642314439512b3058622b7342673d3d34000000656432353531393a38486e7a6b5561583231683939696450676846616a6f56334a5a767933536d4a346d7156775356664c427967")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := nep413.ParseResponse(baseline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *res {
+		t.Fatalf("expected %+v, got %+v", *res, *decoded)
+	}
+
+	// a response without the fields added since still encodes as it did
+	encoded, err := res.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded) != string(baseline) {
+		t.Fatalf("expected %x, got %x", baseline, encoded)
+	}
+
+	// an account id is appended after the baseline fields
+	res.AccountId = "idos.near"
+	encoded, err = res.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(encoded[:len(baseline)]) != string(baseline) {
+		t.Fatalf("expected the baseline form as a prefix of %x", encoded)
+	}
+	if decoded, err = nep413.ParseResponse(encoded); err != nil || *decoded != *res {
+		t.Fatalf("expected %+v, got %+v (%v)", *res, decoded, err)
+	}
+
+	// the unauthenticated state is not encoded
+	withState := *res
+	withState.State = "session-1"
+	if stateEncoded, err := withState.MarshalBinary(); err != nil || string(stateEncoded) != string(encoded) {
+		t.Fatalf("expected state to be left out of %x (%v)", stateEncoded, err)
+	}
+
+	// an empty trailing field is not how MarshalBinary encodes it, so it is rejected
+	if _, err := nep413.ParseResponse(append(append([]byte{}, baseline...), 0, 0, 0, 0)); !errors.Is(err, nep413.ErrMalformedInput) {
+		t.Fatalf("expected ErrMalformedInput, got %v", err)
+	}
+}
+
+func FuzzParseResponse(f *testing.F) {
+	_, res := testVector()
+	res.AccountId = "idos.near"
+
+	data, err := res.MarshalBinary()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add(data[:10])
+	f.Add(binary.LittleEndian.AppendUint32(nil, 0xffffffff))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		decoded, err := nep413.ParseResponse(input)
+		if err != nil {
+			if !errors.Is(err, nep413.ErrMalformedInput) {
+				t.Fatalf("expected ErrMalformedInput, got %v", err)
+			}
+			return
+		}
+
+		// anything that decodes re-encodes to the same bytes
+		encoded, err := decoded.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(encoded) != string(input) {
+			t.Fatalf("round trip mismatch: %x != %x", encoded, input)
+		}
+	})
+}
+
+func Test_ParseMessage(t *testing.T) {
+	msg, _ := testVector()
+	callback := "https://idos.network/callback"
+	msg.CallbackUrl = &callback
+
+	payload := testPayload(t, msg)
+
+	parsed, err := nep413.ParseMessage(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Tag != nep413.Nep413Tag || parsed.Message != msg.Message || parsed.Nonce != msg.Nonce ||
+		parsed.Recipient != msg.Recipient || parsed.CallbackUrl == nil || *parsed.CallbackUrl != callback {
+		t.Fatalf("unexpected message %+v", parsed)
+	}
+
+	// an invalid option tag
+	invalid := append([]byte{}, testPayload(t, &nep413.Nep413Message{Recipient: "idos.network"})...)
+	invalid[len(invalid)-1] = 2
+	if _, err := nep413.ParseMessage(invalid); !errors.Is(err, nep413.ErrMalformedInput) {
+		t.Fatalf("expected ErrMalformedInput, got %v", err)
+	}
+
+	if _, err := nep413.ParseMessage(payload[:len(payload)-1]); !errors.Is(err, nep413.ErrMalformedInput) {
+		t.Fatalf("expected ErrMalformedInput, got %v", err)
+	}
+}
+
+func FuzzParseMessage(f *testing.F) {
+	msg, _ := testVector()
+	f.Add(testPayload(f, msg))
+
+	callback := "https://idos.network/callback"
+	msg.CallbackUrl = &callback
+	f.Add(testPayload(f, msg))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		parsed, err := nep413.ParseMessage(input)
+		if err != nil {
+			if !errors.Is(err, nep413.ErrMalformedInput) {
+				t.Fatalf("expected ErrMalformedInput, got %v", err)
+			}
+			return
+		}
+
+		// anything that decodes re-encodes to the same bytes
+		if encoded := testPayload(t, parsed); parsed.Tag == nep413.Nep413Tag && string(encoded) != string(input) {
+			t.Fatalf("round trip mismatch: %x != %x", encoded, input)
+		}
+	})
+}
+
+func Test_InspectMessageBytes(t *testing.T) {
+	msg, _ := testVector()
+	payload := testPayload(t, msg)
+
+	inspection, err := nep413.InspectMessageBytes(append(payload, 1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inspection.StandardTag || inspection.TrailingBytes != 2 || inspection.Message.Recipient != msg.Recipient {
+		t.Fatalf("unexpected inspection %+v", inspection)
+	}
+
+	// a non-standard tag is reported
+	nonStandard := append([]byte{}, payload...)
+	nonStandard[0] = 0
+	inspection, err = nep413.InspectMessageBytes(nonStandard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inspection.StandardTag {
+		t.Fatal("expected a non-standard tag")
+	}
+
+	// a truncated blob reports the fields before the error
+	inspection, err = nep413.InspectMessageBytes(payload[:len(payload)-3])
+	if !errors.Is(err, nep413.ErrMalformedInput) {
+		t.Fatalf("expected ErrMalformedInput, got %v", err)
+	}
+	if inspection.Message.Message != msg.Message || inspection.Message.Nonce != msg.Nonce {
+		t.Fatalf("expected the decoded fields, got %+v", inspection.Message)
+	}
+}
+
+// borshString appends s to b as a borsh string: its u32 little endian length, then its bytes.
+func borshString(b []byte, s string) []byte {
+	return append(binary.LittleEndian.AppendUint32(b, uint32(len(s))), s...)
+}
+
+func Test_PayloadCallbackUrl(t *testing.T) {
+	msg, _ := testVector()
+	callback := "https://idos.network/callback"
+	empty := ""
+
+	// the payloads near-api-js builds from the NEP-413 schema:
+	// {tag: u32, message: string, nonce: [u8; 32], recipient: string, callbackUrl: Option<string>}
+	prefix := binary.LittleEndian.AppendUint32(nil, 2147484061)
+	prefix = borshString(prefix, msg.Message)
+	prefix = append(prefix, msg.Nonce[:]...)
+	prefix = borshString(prefix, msg.Recipient)
+
+	tests := map[string]struct {
+		callbackUrl *string
+		expected    []byte
+	}{
+		"none":  {nil, append(append([]byte{}, prefix...), 0)},
+		"some":  {&callback, borshString(append(append([]byte{}, prefix...), 1), callback)},
+		"empty": {&empty, borshString(append(append([]byte{}, prefix...), 1), "")},
+	}
+
+	for name, test := range tests {
+		withCallback := *msg
+		withCallback.CallbackUrl = test.callbackUrl
+
+		payload, err := nep413.SerializePayload(&withCallback)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(payload) != string(test.expected) {
+			t.Errorf("%s: expected payload %x, got %x", name, test.expected, payload)
+			continue
+		}
+
+		parsed, err := nep413.ParseMessage(test.expected)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (parsed.CallbackUrl == nil) != (test.callbackUrl == nil) || (parsed.CallbackUrl != nil && *parsed.CallbackUrl != *test.callbackUrl) {
+			t.Errorf("%s: unexpected callback url %v", name, parsed.CallbackUrl)
+		}
+
+		// a signature over the hand built payload verifies
+		hash := sha256.Sum256(test.expected)
+		res, _ := testSign(t, &withCallback, hash[:])
+		if err := nep413.Verify(&withCallback, res); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+// Test_PayloadCallbackUrlVector checks a payload and signature with a callback url that were
+// produced outside of this package: the payload was assembled by hand from the NEP-413 schema
+// in JavaScript, and its sha256 hash signed with Node's built-in ed25519, using the key
+// derived from sha256("nep413 callbackUrl test vector").
+func Test_PayloadCallbackUrlVector(t *testing.T) {
+	callback := "https://idos.network/callback"
+	msg := &nep413.Nep413Message{
+		Message:     "idOS authentication",
+		Recipient:   "idos.network",
+		Nonce:       [32]byte{37, 223, 213, 204, 183, 229, 199, 242, 50, 50, 245, 112, 23, 12, 81, 157, 42, 170, 247, 18, 170, 94, 56, 100, 37, 106, 40, 69, 236, 219, 40, 34},
+		CallbackUrl: &callback,
+	}
+	res := &nep413.Nep413SignatureResponse{
+		Signature: "oazruo3MQAbcVmXZJILn08z8zecvYXx3i7vDNiVmOnvmlwXPQsmoBN8OF2cFbYh5DaLutHPc4vx/TsmY/8nKCQ==",
+		PublicKey: "ed25519:12m8u6Edq4Z2t5wyg7Cfxbj7NeaDv4399QyQD5haNBHx",
+	}
+
+	expected, err := hex.DecodeString("9d0100801300000069644f532061757468656e7469636174696f6e25dfd5ccb7e5c7f23232f570170c519d2aaaf712aa5e3864256a2845ecdb28220c00000069646f732e6e6574776f726b011d00000068747470733a2f2f69646f732e6e6574776f726b2f63616c6c6261636b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload, err := nep413.SerializePayload(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != string(expected) {
+		t.Fatalf("expected payload %x, got %x", expected, payload)
+	}
+
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	// the callback url is signed, so the message without it does not verify
+	withoutCallback := *msg
+	withoutCallback.CallbackUrl = nil
+	if err := nep413.Verify(&withoutCallback, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package nep413
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ErrCallbackHostNotAllowed is returned when the signed callback url's host is not in the allowlist.
+var ErrCallbackHostNotAllowed = errors.New("callback url host is not allowed")
+
+// ErrCallbackRecipientMismatch is returned when RequireCallbackMatchesRecipient is set and the
+// signed callback url's host is not within the recipient's domain.
+var ErrCallbackRecipientMismatch = errors.New("callback url host does not match the recipient")
+
+// WithAllowedCallbackHosts fails verification with ErrCallbackHostNotAllowed if the signed
+// callback url's host is not one of hosts. A host of the form "*.example.com" matches any
+// subdomain of example.com, but not example.com itself. Hosts are compared case-insensitively,
+// and ports are ignored.
+// Messages without a callback url pass; combine with RequireCallbackURL to require one.
+func WithAllowedCallbackHosts(hosts ...string) VerifyOption {
+	allowed := make([]string, len(hosts))
+	for i, host := range hosts {
+		allowed[i] = strings.ToLower(host)
+	}
+
+	return func(c *verifyConfig) {
+		c.allowedCallbackHosts = allowed
+	}
+}
+
+// checkCallbackHost checks the signed callback url's host against the allowlist, if any.
+func (c *verifyConfig) checkCallbackHost(msg *Nep413Message) error {
+	if c.allowedCallbackHosts == nil || msg.CallbackUrl == nil {
+		return nil
+	}
+
+	u, err := url.Parse(*msg.CallbackUrl)
+	if err != nil || u.Hostname() == "" {
+		return ErrCallbackHostNotAllowed
+	}
+
+	if !hostMatchesAny(strings.ToLower(u.Hostname()), c.allowedCallbackHosts) {
+		return ErrCallbackHostNotAllowed
+	}
+
+	return nil
+}
+
+// hostMatchesAny reports whether host matches any of the patterns, which are either exact
+// hosts, or "*." followed by a domain to match its subdomains.
+func hostMatchesAny(host string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequireCallbackMatchesRecipient fails verification with ErrCallbackRecipientMismatch if the
+// recipient is a domain, and the signed callback url's host is neither that domain nor one of
+// its subdomains, tying the callback to the app the user saw. For a recipient of example.com,
+// example.com and app.example.com match, but evilexample.com and example.com.evil.io do not.
+// Hosts are compared case-insensitively, and ports are ignored. The recipient may also be a
+// url, in which case its host is used.
+// Recipients that are NEAR accounts rather than domains (e.g. satoshi.near, or implicit
+// accounts) pass, as do messages without a callback url.
+func RequireCallbackMatchesRecipient() VerifyOption {
+	return func(c *verifyConfig) {
+		c.callbackMatchesRecipient = true
+	}
+}
+
+// checkCallbackRecipient checks the signed callback url's host against the recipient's domain.
+func (c *verifyConfig) checkCallbackRecipient(msg *Nep413Message) error {
+	if !c.callbackMatchesRecipient || msg.CallbackUrl == nil {
+		return nil
+	}
+
+	domain, ok := recipientDomain(msg.Recipient)
+	if !ok {
+		return nil
+	}
+
+	u, err := url.Parse(*msg.CallbackUrl)
+	if err != nil || u.Hostname() == "" {
+		return ErrCallbackRecipientMismatch
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return ErrCallbackRecipientMismatch
+	}
+
+	return nil
+}
+
+// recipientDomain returns the domain of a recipient, lowercased, and reports whether the
+// recipient is a domain at all: a host name with at least two labels, that is not a NEAR
+// account under the near or testnet top-level accounts.
+func recipientDomain(recipient string) (string, bool) {
+	recipient = strings.TrimSpace(recipient)
+	if u, err := url.Parse(recipient); err == nil && u.Scheme != "" && u.Host != "" {
+		recipient = u.Hostname()
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(recipient), ".")
+	dot := strings.LastIndexByte(domain, '.')
+	if dot <= 0 || strings.ContainsAny(domain, "/:@ ") {
+		return "", false
+	}
+
+	switch domain[dot+1:] {
+	case "near", "testnet":
+		return "", false
+	}
+
+	return domain, true
+}
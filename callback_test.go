@@ -0,0 +1,78 @@
+package nep413_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_WithAllowedCallbackHosts(t *testing.T) {
+	tests := []struct {
+		callback string
+		allowed  bool
+	}{
+		{"https://idos.network/callback", true},
+		{"https://IDOS.network:8443/callback", true},
+		{"https://app.example.com/callback", true},
+		{"https://a.b.example.com/callback", true},
+		{"https://example.com/callback", false},
+		{"https://evilexample.com/callback", false},
+		{"https://idos.network.evil.com/callback", false},
+		{"not a url", false},
+	}
+
+	opt := nep413.WithAllowedCallbackHosts("idos.network", "*.example.com")
+	for _, tt := range tests {
+		t.Run(tt.callback, func(t *testing.T) {
+			msg, _ := testVector()
+			callback := tt.callback
+			msg.CallbackUrl = &callback
+			res, _ := testSign(t, msg)
+
+			err := nep413.Verify(msg, res, opt)
+			if tt.allowed && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.allowed && !errors.Is(err, nep413.ErrCallbackHostNotAllowed) {
+				t.Fatalf("expected ErrCallbackHostNotAllowed, got %v", err)
+			}
+		})
+	}
+}
+
+func Test_RequireCallbackMatchesRecipient(t *testing.T) {
+	tests := []struct {
+		recipient string
+		callback  string
+		allowed   bool
+	}{
+		{"idos.network", "https://idos.network/callback", true},
+		{"idos.network", "https://app.IDOS.network:8443/callback", true},
+		{"https://idos.network", "https://app.idos.network/callback", true},
+		{"idos.network", "https://evilidos.network/callback", false},
+		{"idos.network", "https://idos.network.evil.com/callback", false},
+		{"app.idos.network", "https://idos.network/callback", false},
+		{"idos.network", "not a url", false},
+		// NEAR accounts are not domains
+		{"satoshi.near", "https://example.com/callback", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.recipient+" "+tt.callback, func(t *testing.T) {
+			msg, _ := testVector()
+			msg.Recipient = tt.recipient
+			callback := tt.callback
+			msg.CallbackUrl = &callback
+			res, _ := testSign(t, msg)
+
+			err := nep413.Verify(msg, res, nep413.RequireCallbackMatchesRecipient())
+			if tt.allowed && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.allowed && !errors.Is(err, nep413.ErrCallbackRecipientMismatch) {
+				t.Fatalf("expected ErrCallbackRecipientMismatch, got %v", err)
+			}
+		})
+	}
+}
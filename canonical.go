@@ -0,0 +1,35 @@
+package nep413
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CanonicalJSON returns the JSON encoding of v with object keys sorted lexicographically,
+// no insignificant whitespace, and no HTML escaping, so that equal values always encode to
+// the same bytes. It is intended for reproducible logs and for hashing envelopes.
+// It is distinct from the borsch payload that NEP-413 signatures are over.
+func CanonicalJSON(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// decode into generic values, whose maps are encoded with sorted keys
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(generic); err != nil {
+		return nil, err
+	}
+
+	// Encode terminates the value with a newline
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,30 @@
+package nep413_test
+
+import (
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_CanonicalJSON(t *testing.T) {
+	_, res := testVector()
+	res.AccountId = "idos.near"
+
+	data, err := nep413.CanonicalJSON(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"accountId":"idos.near","publicKey":"` + res.PublicKey + `","signature":"` + res.Signature + `"}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+
+	a, err := nep413.CanonicalJSON(map[string]any{"b": 1, "a": map[string]any{"d": "<x>", "c": 2.50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(a) != `{"a":{"c":2.5,"d":"<x>"},"b":1}` {
+		t.Fatalf("unexpected canonical json %s", a)
+	}
+}
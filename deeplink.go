@@ -0,0 +1,74 @@
+package nep413
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrUnsupportedWallet is returned by SignMessageDeepLink for a wallet that cannot be handed
+// a sign-message request through a link.
+var ErrUnsupportedWallet = errors.New("wallet does not accept sign-message links")
+
+// Wallets that SignMessageDeepLink builds links for.
+const (
+	// WalletMyNearWallet is My NEAR Wallet on mainnet
+	WalletMyNearWallet = "mynearwallet"
+	// WalletMyNearWalletTestnet is My NEAR Wallet on testnet
+	WalletMyNearWalletTestnet = "mynearwallet-testnet"
+	// WalletHere is HERE Wallet, which does not accept sign-message links
+	WalletHere = "here"
+	// WalletMeteor is Meteor Wallet, which does not accept sign-message links
+	WalletMeteor = "meteor"
+)
+
+// signMessageURLs are the sign-message pages of the wallets that accept links.
+var signMessageURLs = map[string]string{
+	WalletMyNearWallet:        "https://app.mynearwallet.com/sign-message",
+	WalletMyNearWalletTestnet: "https://testnet.mynearwallet.com/sign-message",
+}
+
+// SignMessageDeepLink builds a link that hands a sign-message request off to a wallet, which
+// opens in the wallet's app or in the browser, and redirects to the callback url with the
+// response in the fragment; see ParseCallbackFragment.
+// The wallets and the links they expect are:
+//   - WalletMyNearWallet and WalletMyNearWalletTestnet: the wallet's sign-message page, as
+//     used by wallet-selector, i.e.
+//     https://app.mynearwallet.com/sign-message?message=...&nonce=<base64>&recipient=...&callbackUrl=...&state=...
+//   - WalletHere and WalletMeteor: not supported, failing with ErrUnsupportedWallet. HERE
+//     Wallet relays requests through its own service, and Meteor Wallet through its SDK,
+//     rather than accepting them in a link; use their SDKs, or wallet-selector, instead.
+//
+// Other wallets fail with ErrUnsupportedWallet too.
+// If callback is not empty, it replaces the params' callback url. The callback url is part of
+// the signed payload, so it must also be set on the message used for verification.
+func SignMessageDeepLink(wallet string, params SignMessageParams, callback string) (string, error) {
+	base, ok := signMessageURLs[wallet]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedWallet, wallet)
+	}
+
+	if callback != "" {
+		params.CallbackUrl = &callback
+	}
+
+	query := url.Values{
+		"message":   {params.Message},
+		"nonce":     {base64.StdEncoding.EncodeToString(params.Nonce[:])},
+		"recipient": {params.Recipient},
+	}
+
+	if params.CallbackUrl != nil {
+		u, err := url.Parse(*params.CallbackUrl)
+		if err != nil || !u.IsAbs() {
+			return "", errors.New("callback url must be an absolute url")
+		}
+		query.Set("callbackUrl", *params.CallbackUrl)
+	}
+	if params.State != "" {
+		query.Set("state", params.State)
+	}
+
+	return base + "?" + query.Encode(), nil
+}
@@ -0,0 +1,51 @@
+package nep413_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_SignMessageDeepLink(t *testing.T) {
+	msg, _ := testVector()
+	params := nep413.SignMessageParams{
+		Message:   msg.Message,
+		Recipient: msg.Recipient,
+		Nonce:     msg.Nonce,
+		State:     "session-1",
+	}
+
+	link, err := nep413.SignMessageDeepLink(nep413.WalletMyNearWallet, params, "https://idos.network/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the query wallet-selector builds for My NEAR Wallet, with its parameters sorted
+	nonce := base64.StdEncoding.EncodeToString(msg.Nonce[:])
+	expected := "https://app.mynearwallet.com/sign-message?callbackUrl=https%3A%2F%2Fidos.network%2Fcallback" +
+		"&message=idOS+authentication&nonce=" + url.QueryEscape(nonce) + "&recipient=idos.network&state=session-1"
+	if link != expected {
+		t.Fatalf("expected %s, got %s", expected, link)
+	}
+
+	link, err = nep413.SignMessageDeepLink(nep413.WalletMyNearWalletTestnet, nep413.SignMessageParams{Recipient: "idos.network"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://testnet.mynearwallet.com/sign-message?message=&nonce=AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA%3D&recipient=idos.network"; link != expected {
+		t.Fatalf("expected %s, got %s", expected, link)
+	}
+
+	for _, wallet := range []string{nep413.WalletHere, nep413.WalletMeteor, "examplewallet"} {
+		if _, err := nep413.SignMessageDeepLink(wallet, params, ""); !errors.Is(err, nep413.ErrUnsupportedWallet) {
+			t.Fatalf("%s: expected ErrUnsupportedWallet, got %v", wallet, err)
+		}
+	}
+
+	if _, err := nep413.SignMessageDeepLink(nep413.WalletMyNearWallet, params, "/relative"); err == nil {
+		t.Fatal("expected error for relative callback")
+	}
+}
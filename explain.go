@@ -0,0 +1,160 @@
+package nep413
+
+import (
+	"context"
+)
+
+// CheckOutcome is the outcome of one policy check in a VerifyExplanation.
+type CheckOutcome struct {
+	// Name identifies the check, e.g. "signature" or "recipient"
+	Name string
+	// Passed is set if the check passed, or was not configured
+	Passed bool
+	// Skipped is set if the check was not evaluated; Detail says why
+	Skipped bool
+	// Err is the error the check failed with, if any
+	Err error
+	// Detail describes the outcome
+	Detail string
+}
+
+// VerifyExplanation lists the outcome of every policy check of a verification.
+type VerifyExplanation struct {
+	// Checks are the outcomes, in the order VerifyContext evaluates them
+	Checks []CheckOutcome
+}
+
+// Passed reports whether every evaluated check passed. Skipped checks are not counted, so
+// a verification may still fail on them.
+func (e *VerifyExplanation) Passed() bool {
+	return e.Err() == nil
+}
+
+// Err returns the error of the first failed check, which VerifyContext would have
+// returned, or nil if none failed.
+func (e *VerifyExplanation) Err() error {
+	for _, check := range e.Checks {
+		if !check.Passed && !check.Skipped {
+			return check.Err
+		}
+	}
+
+	return nil
+}
+
+// Explain evaluates the verifier's full policy against a message and response like
+// VerifyContext, but reports the outcome of every check, rather than stopping at the first
+// failure. It is meant for diagnosing rejected requests.
+// Explain has no side effects: the hooks are not called, Stats are not counted, and no store
+// is written to. Nonce reuse is checked with NonceStore.Has. The rate limit and signature
+// reuse cannot be checked without recording the attempt, so they are skipped. The access key
+// is looked up, since that only reads chain state.
+// The error is only non-nil if ctx is done, with ErrVerificationCancelled.
+func (v *Verifier) Explain(ctx context.Context, msg *Nep413Message, res *Nep413SignatureResponse) (*VerifyExplanation, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	cfg := v.config()
+	explanation := &VerifyExplanation{}
+	add := func(name string, err error, detail string) {
+		explanation.Checks = append(explanation.Checks, CheckOutcome{Name: name, Passed: err == nil, Err: err, Detail: detail})
+	}
+	skip := func(name, detail string) {
+		explanation.Checks = append(explanation.Checks, CheckOutcome{Name: name, Skipped: true, Detail: detail})
+	}
+
+	keyType, err := KeyTypeOf(res.PublicKey)
+	if err == nil {
+		err = cfg.checkKeyType(keyType)
+	}
+	keyTypeErr := err
+	if err != nil {
+		add("key_type", err, "")
+	} else {
+		add("key_type", nil, "public key is "+keyType.String())
+	}
+
+	matchedRecipient, err := cfg.checkRecipient(msg)
+	switch {
+	case err != nil:
+		add("recipient", err, "signed recipient is "+msg.Recipient)
+	case matchedRecipient != "":
+		add("recipient", nil, "matched "+matchedRecipient)
+	default:
+		add("recipient", nil, "no expected recipient")
+	}
+
+	add("message_length", cfg.checkMessageLength(msg), "")
+	add("nonce", cfg.checkNonce(msg), "")
+	add("callback", cfg.checkCallback(msg), "")
+
+	var match *signatureMatch
+	matcher, err := cfg.responseKeyMatcher(res, keyType)
+	if err == nil {
+		if match, err = cfg.matchSignature(msg, matcher); err == nil {
+			msg = match.msg
+		} else if err == ErrNoMatchingKey {
+			err = ErrVerificationFailed
+		}
+	}
+	switch {
+	case keyTypeErr != nil:
+		skip("signature", "key type not allowed")
+	case err != nil:
+		add("signature", err, "")
+	default:
+		add("signature", nil, "valid for "+match.nearKey())
+	}
+
+	// weak keys and implicit accounts only exist for ed25519 keys
+	switch {
+	case match == nil:
+		skip("weak_key", "no valid signature")
+	case match.key == nil:
+		add("weak_key", nil, "not applicable to "+keyType.String()+" keys")
+	case cfg.rejectWeakKeys && isWeakPublicKey(match.key):
+		add("weak_key", ErrWeakPublicKey, "")
+	default:
+		add("weak_key", nil, "")
+	}
+
+	if match != nil && match.key != nil {
+		res, _ = cfg.deriveAccount(res, match.key)
+	}
+	add("account_allowlist", cfg.checkResponse(res), "account id is "+res.AccountId)
+
+	switch {
+	case cfg.accessKeys == nil:
+		add("access_key", nil, "access key check not enabled")
+	case match == nil:
+		skip("access_key", "no valid signature")
+	default:
+		_, err := cfg.checkMatchedAccessKey(ctx, res, match)
+		add("access_key", err, "")
+	}
+
+	if cfg.limiter != nil {
+		skip("rate_limit", "not evaluated, since it would consume the signer's allowance")
+	} else {
+		add("rate_limit", nil, "no rate limit")
+	}
+
+	if cfg.nonceStore == nil {
+		add("nonce_replay", nil, "no nonce store")
+	} else {
+		used, err := cfg.nonceStore.Has(ctx, msg.Recipient, msg.Nonce)
+		if err == nil && used {
+			err = ErrNonceReused
+		}
+		add("nonce_replay", err, "")
+	}
+
+	if cfg.signatureStore != nil {
+		skip("signature_replay", "not evaluated, since it would record the signature")
+	} else {
+		add("signature_replay", nil, "no signature store")
+	}
+
+	return explanation, nil
+}
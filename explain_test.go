@@ -0,0 +1,128 @@
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_Explain(t *testing.T) {
+	store := nep413.NewMemoryNonceStore(time.Hour)
+	v := nep413.NewVerifier(
+		nep413.WithExpectedRecipient("other.app"),
+		nep413.WithAllowedAccounts("idos.near"),
+		nep413.WithNonceStore(store),
+		nep413.WithSignatureDedup(time.Hour),
+	)
+
+	msg, res := testVector()
+	res.AccountId = "attacker.near"
+
+	explanation, err := v.Explain(context.Background(), msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outcomes := make(map[string]nep413.CheckOutcome)
+	for _, check := range explanation.Checks {
+		outcomes[check.Name] = check
+	}
+
+	// every failure is reported, not only the first
+	if !errors.Is(outcomes["recipient"].Err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected recipient mismatch, got %+v", outcomes["recipient"])
+	}
+	if !errors.Is(outcomes["account_allowlist"].Err, nep413.ErrAccountNotAllowed) {
+		t.Fatalf("expected account not allowed, got %+v", outcomes["account_allowlist"])
+	}
+	if !outcomes["signature"].Passed || !outcomes["nonce_replay"].Passed {
+		t.Fatalf("expected signature and nonce to pass, got %+v", explanation.Checks)
+	}
+	if !outcomes["signature_replay"].Skipped {
+		t.Fatal("expected signature reuse to be skipped")
+	}
+
+	if explanation.Passed() || !errors.Is(explanation.Err(), nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected the first failure, got %v", explanation.Err())
+	}
+
+	// explaining has no side effects, so the nonce is still unused
+	msg, res = testVector()
+	res.AccountId = "idos.near"
+	v = nep413.NewVerifier(nep413.WithNonceStore(store), nep413.WithAllowedAccounts("idos.near"))
+
+	explanation, err = v.Explain(context.Background(), msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !explanation.Passed() {
+		t.Fatalf("expected all checks to pass, got %v", explanation.Err())
+	}
+
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot := v.Stats.Snapshot(); snapshot.Attempted != 1 {
+		t.Fatalf("expected only the verification to be counted, got %d", snapshot.Attempted)
+	}
+
+	// once consumed, the nonce is reported as reused
+	explanation, err = v.Explain(context.Background(), msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(explanation.Err(), nep413.ErrNonceReused) {
+		t.Fatalf("expected ErrNonceReused, got %v", explanation.Err())
+	}
+}
+
+func Test_ExplainSecp256k1(t *testing.T) {
+	msg, res := testSecp256k1Vector()
+	v := nep413.NewVerifier()
+
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	explanation, err := v.Explain(context.Background(), msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := explanation.Err(); err != nil {
+		t.Fatalf("expected Explain to agree with Verify, got %v: %+v", err, explanation.Checks)
+	}
+
+	tampered := *msg
+	tampered.Message = "something else"
+	explanation, err = v.Explain(context.Background(), &tampered, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(explanation.Err(), nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", explanation.Err())
+	}
+}
+
+func Test_ExplainKeyType(t *testing.T) {
+	msg, res := testVector()
+	v := nep413.NewVerifier(nep413.RequireKeyType(nep413.KeyTypeSECP256K1))
+
+	_, verifyErr := v.Verify(msg, res)
+	if !errors.Is(verifyErr, nep413.ErrDisallowedKeyType) {
+		t.Fatalf("expected ErrDisallowedKeyType, got %v", verifyErr)
+	}
+
+	explanation, err := v.Explain(context.Background(), msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(explanation.Err(), nep413.ErrDisallowedKeyType) {
+		t.Fatalf("expected Explain to agree with Verify, got %v", explanation.Err())
+	}
+	if explanation.Checks[0].Name != "key_type" {
+		t.Fatalf("expected the key type to be checked first, got %+v", explanation.Checks)
+	}
+}
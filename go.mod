@@ -3,6 +3,12 @@ module github.com/brennanjl/nep413
 go 1.21.0
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.1
+	github.com/fxamacker/cbor/v2 v2.7.0
 	github.com/mr-tron/base58 v1.2.0
 	github.com/near/borsh-go v0.3.1
+	golang.org/x/net v0.25.0
+	golang.org/x/text v0.15.0
 )
+
+require github.com/x448/float16 v0.8.4 // indirect
@@ -0,0 +1,69 @@
+//go:build !(js && wasm)
+
+package nep413
+
+import "net/http"
+
+const (
+	// DefaultTokenHeader is the header AuthReverseProxyGuard reads the auth token from.
+	DefaultTokenHeader = "X-Nep413-Auth"
+	// DefaultAccountHeader is the header AuthReverseProxyGuard forwards the account id in.
+	DefaultAccountHeader = "X-Nep413-Account"
+)
+
+// GuardOption configures AuthReverseProxyGuard.
+type GuardOption func(*guardConfig)
+
+type guardConfig struct {
+	tokenHeader   string
+	accountHeader string
+}
+
+// WithTokenHeader sets the header the auth token is read from.
+func WithTokenHeader(name string) GuardOption {
+	return func(c *guardConfig) {
+		c.tokenHeader = name
+	}
+}
+
+// WithAccountHeader sets the header the verified account id is forwarded in.
+func WithAccountHeader(name string) GuardOption {
+	return func(c *guardConfig) {
+		c.accountHeader = name
+	}
+}
+
+// AuthReverseProxyGuard returns a handler that only forwards requests to next if they
+// carry a valid auth token (see EncodeAuthToken), verified with v. On success, the
+// response's account id is forwarded in the account header; any client-supplied value
+// of that header is always stripped. Requests that fail verification get a 401.
+// The account id is only as trusted as the verifier's policy makes it, so v should
+// bind the account to the key (e.g. with an access key check).
+func AuthReverseProxyGuard(v *Verifier, next http.Handler, opts ...GuardOption) http.Handler {
+	cfg := &guardConfig{
+		tokenHeader:   DefaultTokenHeader,
+		accountHeader: DefaultAccountHeader,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(cfg.accountHeader)
+
+		msg, res, err := ParseAuthToken(r.Header.Get(cfg.tokenHeader))
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		result, err := v.VerifyContext(r.Context(), msg, res)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		r.Header.Set(cfg.accountHeader, result.AccountId)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+//go:build !(js && wasm)
+
+package nep413_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_AuthReverseProxyGuard(t *testing.T) {
+	var forwardedAccount []string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedAccount = r.Header.Values("X-Account")
+	})
+
+	guard := nep413.AuthReverseProxyGuard(nep413.NewVerifier(), next,
+		nep413.WithTokenHeader("X-Auth"), nep413.WithAccountHeader("X-Account"))
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	token, err := nep413.EncodeAuthToken(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Auth", token)
+	req.Header.Set("X-Account", "admin.near")
+	rec := httptest.NewRecorder()
+	guard.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if len(forwardedAccount) != 1 || forwardedAccount[0] != "idos.near" {
+		t.Fatalf("expected forwarded account idos.near, got %v", forwardedAccount)
+	}
+
+	msg.Message = "tampered"
+	token, err = nep413.EncodeAuthToken(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Auth", token)
+	rec = httptest.NewRecorder()
+	guard.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+}
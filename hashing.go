@@ -0,0 +1,101 @@
+package nep413
+
+import (
+	"errors"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidTag is returned when WithPresetTag is set and the message's tag is not the NEP-413
+// tag (or the one given with WithTag).
+var ErrInvalidTag = errors.New("message tag is not the NEP-413 tag")
+
+// WithPresetTag requires the message's tag to already be the NEP-413 tag, failing verification
+// with ErrInvalidTag otherwise, rather than serializing the payload with the NEP-413 tag
+// whatever the message's tag is. The message is never modified either way.
+func WithPresetTag() VerifyOption {
+	return func(c *verifyConfig) {
+		c.presetTag = true
+	}
+}
+
+// WithTag serializes the payload with tag instead of Nep413Tag, e.g. for experimental payload
+// types that NEAR distinguishes from NEP-413 messages by their tag. With WithPresetTag, the
+// message's tag must be tag instead. Signatures made for NEP-413 do not verify with another tag.
+func WithTag(tag uint32) VerifyOption {
+	return func(c *verifyConfig) {
+		c.tag = &tag
+	}
+}
+
+// payloadTag returns the tag the payload is serialized with.
+func (c *verifyConfig) payloadTag() uint32 {
+	if c.tag != nil {
+		return *c.tag
+	}
+
+	return Nep413Tag
+}
+
+// WithMessageNormalization applies the Unicode normalization form to the message text
+// before it is serialized, for wallets that normalize the message before signing it, so that
+// e.g. an "é" composed of "e" and a combining accent verifies against a wallet that signed
+// the precomposed "é". Most wallets do not document whether they normalize; NFC is what
+// JavaScript's String.prototype.normalize() produces by default. Only enable it once a
+// wallet is known to normalize, since it breaks verification of wallets that sign the exact
+// bytes of non-normalized messages. By default, the message is signed as is.
+// Only the message text is normalized, and the caller's message is not modified.
+func WithMessageNormalization(form norm.Form) VerifyOption {
+	return func(c *verifyConfig) {
+		c.messageNormalization = &form
+	}
+}
+
+// payload returns the borsch payload of the message, with the payload tag unless it is preset.
+// The message is not modified.
+func (c *verifyConfig) payload(msg *Nep413Message) ([]byte, error) {
+	if c.messageNormalization != nil {
+		normalized := *msg
+		normalized.Message = c.messageNormalization.String(msg.Message)
+		msg = &normalized
+	}
+
+	if !c.presetTag {
+		return serializeWithTag(msg, c.payloadTag())
+	}
+
+	if msg.Tag != c.payloadTag() {
+		return nil, ErrInvalidTag
+	}
+
+	return encodePayload(msg)
+}
+
+// HashMode selects how the NEP-413 payload is prepared for signing.
+type HashMode uint8
+
+const (
+	// HashSHA256 signs the sha256 hash of the borsch payload, as required by NEP-413.
+	HashSHA256 HashMode = iota
+	// HashNone signs the raw borsch payload. This is NOT part of the NEP-413 spec,
+	// and only exists to interoperate with older, non-conforming wallets.
+	HashNone
+)
+
+// WithPayloadHashing sets how the payload is prepared before verifying the signature.
+// The default is HashSHA256. HashNone is non-standard and should only be used
+// while migrating away from legacy wallets.
+func WithPayloadHashing(mode HashMode) VerifyOption {
+	return func(c *verifyConfig) {
+		c.hashMode = mode
+	}
+}
+
+// signedBytes returns the bytes the signature is expected to be over.
+func (c *verifyConfig) signedBytes(payload []byte, hash [32]byte) []byte {
+	if c.hashMode == HashNone {
+		return payload
+	}
+
+	return hash[:]
+}
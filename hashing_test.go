@@ -0,0 +1,106 @@
+package nep413_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	borsch "github.com/near/borsh-go"
+	"golang.org/x/text/unicode/norm"
+)
+
+func Test_WithPayloadHashing(t *testing.T) {
+	msg, _ := testVector()
+
+	// legacy wallets sign the raw payload
+	res, _ := testSign(t, msg, testPayload(t, msg))
+
+	if err := nep413.Verify(msg, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	if err := nep413.Verify(msg, res, nep413.WithPayloadHashing(nep413.HashNone)); err != nil {
+		t.Fatal(err)
+	}
+
+	hashed, _ := testSign(t, msg)
+	if err := nep413.Verify(msg, hashed, nep413.WithPayloadHashing(nep413.HashSHA256)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_WithPresetTag(t *testing.T) {
+	msg, res := testVector()
+
+	if err := nep413.Verify(msg, res, nep413.WithPresetTag()); !errors.Is(err, nep413.ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+	if msg.Tag != 0 {
+		t.Fatal("expected the tag to be left untouched")
+	}
+
+	msg.Tag = nep413.Nep413Tag
+	if err := nep413.Verify(msg, res, nep413.WithPresetTag()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_WithTag(t *testing.T) {
+	msg, _ := testVector()
+	msg.Tag = 1<<31 + 414
+
+	// signed over the payload with the experimental tag
+	payload, err := borsch.Serialize(*msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(payload)
+	res, _ := testSign(t, msg, hash[:])
+
+	if err := nep413.Verify(msg, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+	if err := nep413.Verify(msg, res, nep413.WithTag(msg.Tag)); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.Verify(msg, res, nep413.WithTag(msg.Tag), nep413.WithPresetTag()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tag is used regardless of the message's, unless it is preset
+	untagged := *msg
+	untagged.Tag = nep413.Nep413Tag
+	if err := nep413.Verify(&untagged, res, nep413.WithTag(msg.Tag)); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.Verify(&untagged, res, nep413.WithTag(msg.Tag), nep413.WithPresetTag()); !errors.Is(err, nep413.ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+}
+
+func Test_WithMessageNormalization(t *testing.T) {
+	// the wallet signed the precomposed "é"
+	signed, _ := testVector()
+	signed.Message = "caf\u00e9"
+	res, _ := testSign(t, signed)
+
+	// the server reconstructs the message with a combining accent
+	msg := *signed
+	msg.Message = "cafe\u0301"
+
+	if err := nep413.Verify(&msg, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	if err := nep413.Verify(&msg, res, nep413.WithMessageNormalization(norm.NFC)); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Message != "cafe\u0301" {
+		t.Fatal("expected the message to be unchanged")
+	}
+
+	if err := nep413.Verify(&msg, res, nep413.WithMessageNormalization(norm.NFD)); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+}
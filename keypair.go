@@ -0,0 +1,52 @@
+package nep413
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// KeyPair is an ed25519 key pair that can produce NEP-413 signatures.
+type KeyPair struct {
+	PrivateKey ed25519.PrivateKey
+}
+
+// PublicKey returns the public half of the key pair.
+func (k *KeyPair) PublicKey() ed25519.PublicKey {
+	return k.PrivateKey.Public().(ed25519.PublicKey)
+}
+
+// Sign signs msg with the key pair, like Sign.
+func (k *KeyPair) Sign(msg *Nep413Message) (*Nep413SignatureResponse, error) {
+	return Sign(msg, k.PrivateKey)
+}
+
+// ParseKeyPair parses a NEAR formatted private key, e.g. "ed25519:base58_encoded_private_key".
+// Both the 64 byte secret key used by near-cli and a 32 byte seed are accepted.
+func ParseKeyPair(s string) (*KeyPair, error) {
+	encoded, ok := strings.CutPrefix(s, "ed25519:")
+	if !ok {
+		return nil, errors.New("invalid private key format, expected ed25519:base58_encoded_private_key")
+	}
+
+	keyBytes, err := decodeBase58(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key encoding: %w", err)
+	}
+
+	switch len(keyBytes) {
+	case ed25519.SeedSize:
+		return &KeyPair{PrivateKey: ed25519.NewKeyFromSeed(keyBytes)}, nil
+	case ed25519.PrivateKeySize:
+		// the second half of a secret key is its public key, which must match the seed
+		priv := ed25519.NewKeyFromSeed(keyBytes[:ed25519.SeedSize])
+		if !bytes.Equal(priv, keyBytes) {
+			return nil, errors.New("invalid private key, public key does not match seed")
+		}
+		return &KeyPair{PrivateKey: priv}, nil
+	default:
+		return nil, fmt.Errorf("invalid private key length, expected %d or %d, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(keyBytes))
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !(js && wasm)
+
+package nep413
+
+import (
+	"fmt"
+	"os"
+)
+
+// LoadKeyPairFromEnv loads a NEAR formatted private key ("ed25519:base58_encoded_private_key")
+// from the given environment variable.
+func LoadKeyPairFromEnv(varName string) (*KeyPair, error) {
+	value, ok := os.LookupEnv(varName)
+	if !ok || value == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", varName)
+	}
+
+	keyPair, err := ParseKeyPair(value)
+	if err != nil {
+		// the value is a secret, so it is not included in the error
+		return nil, fmt.Errorf("environment variable %s: %w", varName, err)
+	}
+
+	return keyPair, nil
+}
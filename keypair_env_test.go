@@ -0,0 +1,48 @@
+//go:build !(js && wasm)
+
+package nep413_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+)
+
+func Test_LoadKeyPairFromEnv(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"secret key": "ed25519:" + base58.Encode(priv),
+		"seed":       "ed25519:" + base58.Encode(priv.Seed()),
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("NEP413_TEST_KEY", value)
+
+			keyPair, err := nep413.LoadKeyPairFromEnv("NEP413_TEST_KEY")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(keyPair.PublicKey(), pub) {
+				t.Fatal("loaded key does not match")
+			}
+		})
+	}
+
+	if _, err := nep413.LoadKeyPairFromEnv("NEP413_TEST_UNSET_KEY"); err == nil {
+		t.Fatal("expected error for unset variable")
+	}
+
+	t.Setenv("NEP413_TEST_KEY", base58.Encode(priv))
+	if _, err := nep413.LoadKeyPairFromEnv("NEP413_TEST_KEY"); err == nil {
+		t.Fatal("expected error for missing prefix")
+	}
+}
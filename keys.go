@@ -0,0 +1,114 @@
+package nep413
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+// Base58Alphabet is the base58 alphabet NEAR encodes keys with: Bitcoin's.
+const Base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// nearAlphabet is Base58Alphabet, for encoding and decoding.
+var nearAlphabet = base58.NewAlphabet(Base58Alphabet)
+
+// ErrInvalidBase58 is returned when a key contains characters outside of Base58Alphabet,
+// e.g. because it was encoded with another base58 alphabet.
+var ErrInvalidBase58 = errors.New("invalid base58 encoding")
+
+// decodeBase58 decodes s with Base58Alphabet, failing with ErrInvalidBase58 on the first
+// character outside of it, rather than decoding to the wrong bytes.
+func decodeBase58(s string) ([]byte, error) {
+	for i, r := range s {
+		if !strings.ContainsRune(Base58Alphabet, r) {
+			return nil, fmt.Errorf("%w: character %q at position %d is not in the alphabet %s", ErrInvalidBase58, r, i, Base58Alphabet)
+		}
+	}
+
+	return base58.DecodeAlphabet(s, nearAlphabet)
+}
+
+// FormatPublicKey formats a public key the way NEAR does, e.g.
+// "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg".
+func FormatPublicKey(pub ed25519.PublicKey) string {
+	return "ed25519:" + base58.EncodeAlphabet(pub, nearAlphabet)
+}
+
+// ParsePublicKey parses a public key in NEAR's format: "ed25519:" followed by the 32 key
+// bytes, either base58 encoded with Base58Alphabet (as NEAR formats keys, e.g.
+// "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg"), or as 64 hex characters (as some
+// tools output them). The encodings cannot be confused, since 32 bytes are at most 44
+// characters in base58.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	// the first part is the algorithm, and the second part is the encoded public key
+	algorithm, encoded, ok := strings.Cut(s, ":")
+	if !ok || algorithm != "ed25519" || strings.Contains(encoded, ":") {
+		return nil, errors.New("invalid public key format, expected ed25519:base58_encoded_public_key")
+	}
+
+	if len(encoded) == hex.EncodedLen(ed25519.PublicKeySize) {
+		return ParsePublicKeyHex(encoded)
+	}
+
+	pubkeyBytes, err := decodeBase58(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pubkeyBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length, expected %d, got %d", ed25519.PublicKeySize, len(pubkeyBytes))
+	}
+
+	return pubkeyBytes, nil
+}
+
+// ParsePublicKeyLenient parses a public key like ParsePublicKey, after removing what config
+// files and copy-pasting commonly add around it, in this order:
+//  1. leading and trailing whitespace, as defined by unicode.IsSpace (including newlines)
+//  2. one pair of matching double quotes, single quotes or backticks around the key
+//  3. whitespace inside those quotes
+//
+// Nothing inside the key itself is changed. ParsePublicKey stays strict, and rejects all of these.
+func ParsePublicKeyLenient(s string) (ed25519.PublicKey, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && strings.ContainsRune("\"'`", rune(s[0])) && s[len(s)-1] == s[0] {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	return ParsePublicKey(s)
+}
+
+// ParsePublicKeyHex parses a public key given as 64 hex characters, without a prefix,
+// e.g. an implicit account id.
+func ParsePublicKeyHex(s string) (ed25519.PublicKey, error) {
+	if len(s) != hex.EncodedLen(ed25519.PublicKeySize) {
+		return nil, fmt.Errorf("invalid public key length, expected %d hex characters, got %d", hex.EncodedLen(ed25519.PublicKeySize), len(s))
+	}
+
+	pubkeyBytes, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding: %w", err)
+	}
+
+	return pubkeyBytes, nil
+}
+
+// IsValidPublicKeyString reports whether s is a well formed NEAR ed25519 public key,
+// e.g. for validating configured keys at startup.
+func IsValidPublicKeyString(s string) bool {
+	_, err := ParsePublicKey(s)
+	return err == nil
+}
+
+// KeyFingerprint returns a short, stable identifier for a public key:
+// the first 8 bytes of its sha256 hash, hex encoded.
+// It is meant for logs and map keys, not for security decisions.
+func KeyFingerprint(pub ed25519.PublicKey) string {
+	hash := sha256.Sum256(pub)
+	return hex.EncodeToString(hash[:8])
+}
@@ -0,0 +1,125 @@
+package nep413_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_KeyFingerprint(t *testing.T) {
+	_, res := testVector()
+
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := nep413.KeyFingerprint(pub)
+	if len(fingerprint) != 16 {
+		t.Fatalf("expected 16 hex characters, got %s", fingerprint)
+	}
+
+	if fingerprint != nep413.KeyFingerprint(pub) {
+		t.Fatal("expected fingerprint to be stable")
+	}
+}
+
+func Test_IsValidPublicKeyString(t *testing.T) {
+	cases := map[string]bool{
+		"ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg":   true,
+		"8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg":           false,
+		"secp256k1:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg": false,
+		"ed25519:8HnzkUaX21h99idPghFajoV3JZvy3S":                 false,
+		"ed25519:0OIl":                                           false,
+		"ed25519:a:b":                                            false,
+	}
+
+	for s, expected := range cases {
+		if nep413.IsValidPublicKeyString(s) != expected {
+			t.Fatalf("%s: expected valid = %v", s, expected)
+		}
+	}
+}
+
+func Test_ParsePublicKeyHex(t *testing.T) {
+	expected, err := nep413.ParsePublicKey("ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := hex.EncodeToString(expected)
+
+	pub, err := nep413.ParsePublicKeyHex(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(expected) {
+		t.Fatal("expected the hex key to match")
+	}
+
+	// the main parser accepts either encoding
+	pub, err = nep413.ParsePublicKey("ed25519:" + strings.ToUpper(encoded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(expected) {
+		t.Fatal("expected the hex key to match")
+	}
+
+	for _, invalid := range []string{encoded[:62], encoded + "00", "zz" + encoded[2:], "ed25519:" + encoded} {
+		if _, err := nep413.ParsePublicKeyHex(invalid); err == nil {
+			t.Fatalf("expected %s to be invalid", invalid)
+		}
+	}
+}
+
+func Test_ParsePublicKeyAlphabet(t *testing.T) {
+	// "0", "O", "I" and "l" are not in Bitcoin's alphabet
+	for _, invalid := range []string{"ed25519:0HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg", "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByl"} {
+		_, err := nep413.ParsePublicKey(invalid)
+		if !errors.Is(err, nep413.ErrInvalidBase58) {
+			t.Fatalf("%s: expected ErrInvalidBase58, got %v", invalid, err)
+		}
+	}
+
+	if len(nep413.Base58Alphabet) != 58 {
+		t.Fatalf("expected 58 characters, got %d", len(nep413.Base58Alphabet))
+	}
+}
+
+func Test_ParsePublicKeyLenient(t *testing.T) {
+	_, res := testVector()
+	want, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		res.PublicKey:                           true,
+		"  " + res.PublicKey + "\n":             true,
+		`"` + res.PublicKey + `"`:               true,
+		"'" + res.PublicKey + "'":               true,
+		"\t` " + res.PublicKey + " `\r\n":       true,
+		`"` + res.PublicKey + `'`:               false,
+		`""` + res.PublicKey + `""`:             false,
+		"ed25519: 8HnzkUaX21h99idPghFajoV3JZvy": false,
+	}
+
+	for s, valid := range tests {
+		pub, err := nep413.ParsePublicKeyLenient(s)
+		if valid && (err != nil || !pub.Equal(want)) {
+			t.Fatalf("expected %q to parse, got %v", s, err)
+		}
+		if !valid && err == nil {
+			t.Fatalf("expected %q to be rejected", s)
+		}
+
+		// strict parsing rejects anything around the key
+		if _, err := nep413.ParsePublicKey(s); s != res.PublicKey && err == nil {
+			t.Fatalf("expected ParsePublicKey to reject %q", s)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !(js && wasm)
+
+package nep413
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Defaults used by VerifyNamedAccount.
+const (
+	namedAccountCacheTTL     = time.Minute
+	namedAccountRetries      = 2
+	namedAccountRetryBackoff = 100 * time.Millisecond
+)
+
+// namedAccountVerifiers holds the Verifier used by VerifyNamedAccount for each rpc url,
+// so that the access key cache is shared between calls.
+var namedAccountVerifiers sync.Map
+
+// VerifyNamedAccount is the turnkey path for authenticating a named account, e.g. "idos.near":
+// it verifies the signature, and checks that the signing key is a full access key of the
+// response's account on chain, using the NEAR JSON-RPC endpoint at rpcURL. Failed requests
+// are retried twice, and access keys are cached for a minute, in a cache shared by all calls
+// with the same rpcURL. For other policies, build a Verifier with WithAccessKeyCheck.
+//
+// Each stage fails with a distinct error:
+//   - an invalid account id: an *AccountIDError, wrapping ErrInvalidAccountID
+//   - an invalid signature: ErrVerificationFailed
+//   - no account id: ErrMissingAccountID
+//   - a key that is not on the account: ErrAccessKeyNotFound
+//   - a function call key: ErrNotFullAccessKey
+//   - an unreachable node: an error wrapping the RPC error
+//   - a cancelled ctx: ErrVerificationCancelled
+func VerifyNamedAccount(ctx context.Context, msg *Nep413Message, res *Nep413SignatureResponse, rpcURL string) (*VerifyResult, error) {
+	if res.AccountId != "" {
+		if err := ValidateAccountID(normalizeAccountID(res.AccountId)); err != nil {
+			return nil, err
+		}
+	}
+
+	v, ok := namedAccountVerifiers.Load(rpcURL)
+	if !ok {
+		client := NewRPCClient(rpcURL, WithRetries(namedAccountRetries, namedAccountRetryBackoff))
+		v, _ = namedAccountVerifiers.LoadOrStore(rpcURL, NewVerifier(
+			WithAccessKeyCheck(client),
+			WithAccessKeyCache(namedAccountCacheTTL),
+			RequireFullAccessKey(),
+		))
+	}
+
+	return v.(*Verifier).VerifyContext(ctx, msg, res)
+}
@@ -0,0 +1,53 @@
+//go:build !(js && wasm)
+
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifyNamedAccount(t *testing.T) {
+	msg, res := testVector()
+	callRes, _ := testSign(t, msg)
+
+	server := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {
+			res.PublicKey:     `"FullAccess"`,
+			callRes.PublicKey: `{"FunctionCall": {"allowance": null, "receiver_id": "idos.near", "method_names": []}}`,
+		},
+	})
+	ctx := context.Background()
+
+	res.AccountId = "idos.near"
+	result, err := nep413.VerifyNamedAccount(ctx, msg, res, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccessKey == nil || !result.AccessKey.FullAccess {
+		t.Fatalf("unexpected access key %+v", result.AccessKey)
+	}
+
+	callRes.AccountId = "idos.near"
+	if _, err := nep413.VerifyNamedAccount(ctx, msg, callRes, server.URL); !errors.Is(err, nep413.ErrNotFullAccessKey) {
+		t.Fatalf("expected ErrNotFullAccessKey, got %v", err)
+	}
+
+	res.AccountId = "attacker.near"
+	if _, err := nep413.VerifyNamedAccount(ctx, msg, res, server.URL); !errors.Is(err, nep413.ErrAccessKeyNotFound) {
+		t.Fatalf("expected ErrAccessKeyNotFound, got %v", err)
+	}
+
+	res.AccountId = "idos..near"
+	if _, err := nep413.VerifyNamedAccount(ctx, msg, res, server.URL); !errors.Is(err, nep413.ErrInvalidAccountID) {
+		t.Fatalf("expected ErrInvalidAccountID, got %v", err)
+	}
+
+	res.AccountId = ""
+	if _, err := nep413.VerifyNamedAccount(ctx, msg, res, server.URL); !errors.Is(err, nep413.ErrMissingAccountID) {
+		t.Fatalf("expected ErrMissingAccountID, got %v", err)
+	}
+}
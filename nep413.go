@@ -1,66 +1,191 @@
 package nep413
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/mr-tron/base58"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/fxamacker/cbor/v2"
 	borsch "github.com/near/borsh-go"
 )
 
 // nep413SignatureResponse is the response from an NEP-413 signature.
 // it implements the encoding.BinaryMarshaler and encoding.BinaryUnmarshaler interfaces.
 // it utilizes borsch for deterministic serialization.
-// In the NEP-413 spec, the NEAR address of the caller is included.
-// This is not included in this implementation, as it is not needed for verification.
+// In the NEP-413 spec, the NEAR address of the caller is included as AccountId.
+// It is not covered by the signature, and is not needed for verification.
 type Nep413SignatureResponse struct {
-	// Signature is the base64 encoded signature
-	Signature string
-	// PublicKey is the hex encoded public key, prepending with NEAR's "ed25519"
-	// ex: "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg"
-	PublicKey string
+	// Signature is the base64 encoded signature. NEAR's format, the key type's prefix followed
+	// by the base58 encoded signature (e.g. "ed25519:25qQjLe4..."), is also accepted.
+	Signature string `json:"signature"`
+	// PublicKey is the base58 encoded public key, prepended with NEAR's "ed25519:"
+	// ex: "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg".
+	// A 64 character hex encoding is also accepted; see ParsePublicKey.
+	// secp256k1 keys are prepended with "secp256k1:" instead; see ParseSecp256k1PublicKey.
+	PublicKey string `json:"publicKey"`
+	// AccountId is the NEAR account that claims to have signed the message (e.g. satoshi.near)
+	AccountId string `json:"accountId,omitempty"`
+	// State is an opaque value the wallet echoes back from the request, if any.
+	// Like AccountId, it is not covered by the signature; see AssertState. It is not encoded
+	// by MarshalBinary.
+	State string `json:"state,omitempty"`
 }
 
-// PubKey returns the ed25519 public key
+// PubKey returns the ed25519 public key. It fails for secp256k1 keys; see KeyTypeOf.
 func (n *Nep413SignatureResponse) PubKey() (ed25519.PublicKey, error) {
-	// NEAR's public keys are in the format ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg
-	// where the first part is the algorithm, and the second part is the base58 encoded public key
-	splitKey := strings.Split(n.PublicKey, ":")
-	if len(splitKey) != 2 {
-		return nil, errors.New("invalid public key format, expected ed25519:base58_encoded_public_key")
-	}
+	return ParsePublicKey(n.PublicKey)
+}
+
+// binaryResponse is the borsch form of a response written before it had an account id.
+type binaryResponse struct {
+	Signature string
+	PublicKey string
+}
 
-	// decode the public key
-	pubkeyBytes, err := base58.Decode(splitKey[1])
+// MarshalBinary encodes the response as the borsch serialization of its signature and public
+// key, which is the form earlier versions wrote, followed by the fields added since, as borsch
+// strings, up to the last one that is set. A response with none of them set encodes exactly
+// as it did before.
+func (n Nep413SignatureResponse) MarshalBinary() ([]byte, error) {
+	data, err := borsch.Serialize(binaryResponse{Signature: n.Signature, PublicKey: n.PublicKey})
 	if err != nil {
 		return nil, err
 	}
 
-	if len(pubkeyBytes) != ed25519.PublicKeySize {
-		return nil, fmt.Errorf("invalid public key length, expected %d, got %d", ed25519.PublicKeySize, len(pubkeyBytes))
+	extra := n.binaryExtraFields()
+	for len(extra) > 0 && *extra[len(extra)-1] == "" {
+		extra = extra[:len(extra)-1]
+	}
+	for _, field := range extra {
+		encoded, err := borsch.Serialize(*field)
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, encoded...)
 	}
 
-	return pubkeyBytes, nil
+	return data, nil
 }
 
-func (n Nep413SignatureResponse) MarshalBinary() ([]byte, error) {
-	return borsch.Serialize(n)
+// binaryExtraFields are the fields encoded after the signature and public key, in order.
+// State is unauthenticated, and only meaningful to the request that set it, so it is not
+// part of the binary form.
+func (n *Nep413SignatureResponse) binaryExtraFields() []*string {
+	return []*string{&n.AccountId}
 }
 
+// UnmarshalBinary decodes a response produced by MarshalBinary. The input is bounds
+// checked, so malformed or oversized input fails with ErrMalformedInput.
 func (n *Nep413SignatureResponse) UnmarshalBinary(data []byte) error {
-	return borsch.Deserialize(n, data)
+	res, err := ParseResponse(data)
+	if err != nil {
+		return err
+	}
+
+	*n = *res
+	return nil
+}
+
+// cborResponse has the same fields as Nep413SignatureResponse, without its methods,
+// so that the cbor library does not recurse into MarshalCBOR / UnmarshalCBOR.
+type cborResponse Nep413SignatureResponse
+
+// MarshalCBOR encodes the response as a CBOR map, keyed by the same names as its JSON form.
+// This is only for transporting responses; the signed payload is always borsch.
+func (n Nep413SignatureResponse) MarshalCBOR() ([]byte, error) {
+	return cbor.Marshal(cborResponse(n))
+}
+
+func (n *Nep413SignatureResponse) UnmarshalCBOR(data []byte) error {
+	return cbor.Unmarshal(data, (*cborResponse)(n))
 }
 
+// ResponsesEqual reports whether a and b are the same response, e.g. to check that an incoming
+// response is the one stored earlier. The account ids are compared in normalized form (see
+// EqualAccountID), and the signatures and public keys by their decoded bytes, so that
+// differences in encoding, such as base64 padding or a hex rather than base58 key, do not
+// matter; fields that fail to decode are compared as given. All fields are compared in
+// constant time, and all of them are always compared. State is not compared, since it is
+// not part of what the wallet signed. It returns false if either response is nil.
+func ResponsesEqual(a, b *Nep413SignatureResponse) bool {
+	if a == nil || b == nil {
+		return false
+	}
+
+	equal := 1
+	if !EqualAccountID(a.AccountId, b.AccountId) {
+		equal = 0
+	}
+	equal &= subtle.ConstantTimeCompare(responseSignatureBytes(a.Signature, a.PublicKey), responseSignatureBytes(b.Signature, b.PublicKey))
+	equal &= subtle.ConstantTimeCompare(responseKeyBytes(a.PublicKey), responseKeyBytes(b.PublicKey))
+
+	return equal == 1
+}
+
+// responseSignatureBytes returns the decoded bytes of a response's signature, in either of
+// the formats verification accepts, or the signature as given if it cannot be decoded. The
+// signature of a secp256k1 key is reduced to its canonical r||s, like in a SignatureStore,
+// so that it is equal with and without its recovery id. The bytes are prefixed, so that a
+// signature that cannot be decoded never equals one that can.
+func responseSignatureBytes(signature, publicKey string) []byte {
+	var decoded []byte
+	var err error
+	if _, encoded, ok := strings.Cut(signature, ":"); ok {
+		decoded, err = decodeBase58(encoded)
+	} else {
+		// padding is optional, so that it does not affect the comparison
+		decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(signature, "="))
+	}
+	if err != nil {
+		return append([]byte{0}, signature...)
+	}
+
+	if keyType, err := KeyTypeOf(publicKey); err == nil && keyType == KeyTypeSECP256K1 {
+		if canonical, ok := canonicalSecp256k1Signature(decoded); ok {
+			decoded = canonical
+		}
+	}
+
+	return append([]byte{1}, decoded...)
+}
+
+// responseKeyBytes returns the key type and decoded bytes of a response's public key, or the
+// key as given if it cannot be parsed, prefixed like responseSignatureBytes.
+func responseKeyBytes(key string) []byte {
+	if keyType, err := KeyTypeOf(key); err == nil {
+		switch keyType {
+		case KeyTypeSECP256K1:
+			if pub, err := ParseSecp256k1PublicKey(key); err == nil {
+				return append([]byte{1, byte(keyType)}, pub.SerializeUncompressed()[1:]...)
+			}
+		default:
+			if pub, err := ParsePublicKey(key); err == nil {
+				return append([]byte{1, byte(keyType)}, pub...)
+			}
+		}
+	}
+
+	return append([]byte{0}, key...)
+}
+
+// Nep413Tag is the tag prefixed to every NEP-413 payload: 2^31 + 413, or 2147484061.
+// Setting bit 31 keeps signed messages distinct from transactions, and 413 is the NEP number.
+// https://github.com/near/NEPs/blob/master/neps/nep-0413.md#example
+const Nep413Tag uint32 = 1<<31 + 413
+
 // Nep413Message is the message sent to the NEP-413 signer.
 // it utilizes borsch for deterministic serialization
 type Nep413Message struct {
 	// Tag is some NEAR specific thing that is not really explained anywhere,
-	// but should always be the number 2^31+413, or 2147484061
-	// https://github.com/near/NEPs/blob/master/neps/nep-0413.md#example
+	// but should always be Nep413Tag. Verification and signing use Nep413Tag regardless
+	// of its value (see WithTag and WithPresetTag to change that).
 	Tag uint32
 
 	// Message is the plaintext message
@@ -72,43 +197,390 @@ type Nep413Message struct {
 	// Recipient is the string identifier of the recipient (e.g. satoshi.near)
 	Recipient string
 
-	// CallbackUrl is the url to call when the signature is ready
+	// CallbackUrl is the url to call when the signature is ready. It is serialized as the
+	// borsch Option<string> of the NEP-413 schema: nil is None, and any string, even an
+	// empty one, is Some.
 	CallbackUrl *string
 }
 
+// fingerprintFields are the fields of a message that RequestFingerprint is computed over.
+type fingerprintFields struct {
+	Message     string
+	Nonce       [32]byte
+	Recipient   string
+	CallbackUrl *string
+}
+
+// RequestFingerprint returns a stable identifier for a sign request: the hex encoded sha256
+// hash of the borsch serialization of its message, nonce, recipient and callback url. The tag,
+// which is always Nep413Tag, is left out, so the fingerprint does not depend on whether it was set.
+// It is meant for deduplicating retried requests at the application layer, and is the same
+// for every signature of the request. It is not a security boundary: anyone can compute it,
+// and it says nothing about whether a signature is valid.
+func RequestFingerprint(msg *Nep413Message) string {
+	// borsch cannot fail to serialize these field types
+	data, _ := borsch.Serialize(fingerprintFields{
+		Message:     msg.Message,
+		Nonce:       msg.Nonce,
+		Recipient:   msg.Recipient,
+		CallbackUrl: msg.CallbackUrl,
+	})
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// ErrVerificationFailed is returned when a signature does not verify against the payload.
+var ErrVerificationFailed = errors.New("signature verification failed")
+
+// ErrNoMatchingKey is returned by VerifyWithCandidateKeys when none of the candidate keys
+// produced a valid signature check.
+var ErrNoMatchingKey = errors.New("no candidate key matched the signature")
+
 // Verify verifies an NEP-413 signature.
 // It is based on the implementation found here: https://github.com/gagdiez/near-login/blob/3c0ad7d6587c835202b06d36afbde50ee6c6fec9/tests/authentication/wallet.ts#L60
-func Verify(msg *Nep413Message, res *Nep413SignatureResponse) error {
-	msg.Tag = 2147484061
+// Options can be passed to apply additional checks to the signed message.
+// The message and response are not modified, so they can be shared by concurrent verifications.
+// Both ed25519 and secp256k1 keys are supported, according to the response's key type.
+func Verify(msg *Nep413Message, res *Nep413SignatureResponse, opts ...VerifyOption) error {
+	_, err := verifyResponseKey(context.Background(), newVerifyConfig(opts), msg, res)
+	return err
+}
 
-	// cast the sender to an ed25519 public key
-	publicKey, err := res.PubKey()
+// VerifyWithKey verifies an NEP-413 signature against pub, ignoring the public key in the
+// response; only its signature and account id are used.
+// Prefer it over Verify whenever the expected key is known out-of-band: the response's key is
+// attacker-supplied, so trusting it lets an attacker swap in their own key and signature
+// (a "key confusion" attack), unless the key is separately bound to the account.
+func VerifyWithKey(msg *Nep413Message, res *Nep413SignatureResponse, pub ed25519.PublicKey, opts ...VerifyOption) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key length, expected %d, got %d", ed25519.PublicKeySize, len(pub))
+	}
+
+	_, err := VerifyWithCandidateKeys(msg, res, []ed25519.PublicKey{pub}, opts...)
+	if errors.Is(err, ErrNoMatchingKey) {
+		return ErrVerificationFailed
+	}
+
+	return err
+}
+
+// VerifyHash verifies an ed25519 signature over a precomputed NEP-413 payload hash, e.g. one
+// computed by a hardware module. It only performs the signature check, and returns
+// ErrVerificationFailed for invalid signatures, like Verify.
+func VerifyHash(hash [32]byte, sig []byte, pub ed25519.PublicKey) error {
+	if len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key length, expected %d, got %d", ed25519.PublicKeySize, len(pub))
+	}
+
+	if len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("%w: invalid signature length, expected %d, got %d", ErrVerificationFailed, ed25519.SignatureSize, len(sig))
+	}
+
+	if !ed25519.Verify(pub, hash[:], sig) {
+		return ErrVerificationFailed
+	}
+
+	return nil
+}
+
+// VerifyRawPayload verifies an ed25519 signature over a complete borsch serialized NEP-413
+// payload, for systems that transmit the signed payload rather than its fields. The payload
+// must decode as a message (see ParseMessage) tagged with Nep413Tag, or verification fails
+// with ErrMalformedInput or ErrInvalidTag; its sha256 hash is then verified like VerifyHash.
+// Only the signature is checked: use ParseMessage to apply a policy to the decoded message.
+func VerifyRawPayload(payload []byte, sig []byte, pub ed25519.PublicKey) error {
+	msg, err := ParseMessage(payload)
 	if err != nil {
 		return err
 	}
 
+	if msg.Tag != Nep413Tag {
+		return ErrInvalidTag
+	}
+
+	return VerifyHash(sha256.Sum256(payload), sig, pub)
+}
+
+// ErrVerificationCancelled is returned when the context of a verification is cancelled, or its
+// deadline exceeded, e.g. during an access key lookup. It is returned together with the
+// context's error, so errors.Is(err, context.DeadlineExceeded) still holds. Only verifications
+// given a context, such as Verifier.VerifyContext, can be cancelled.
+var ErrVerificationCancelled = errors.New("verification cancelled")
+
+// contextErr returns an error wrapping ErrVerificationCancelled if ctx is done.
+func contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrVerificationCancelled, err)
+	}
+
+	return nil
+}
+
+// VerifyWithCandidateKeys verifies an NEP-413 signature against each of the given keys,
+// ignoring the public key claimed in the response. It returns the first key that validates
+// the signature, or ErrNoMatchingKey if none do.
+// This is useful when the response's public key cannot be trusted, but the account's key set is known.
+func VerifyWithCandidateKeys(msg *Nep413Message, res *Nep413SignatureResponse, keys []ed25519.PublicKey, opts ...VerifyOption) (ed25519.PublicKey, error) {
+	result, err := verify(context.Background(), newVerifyConfig(opts), msg, res, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.PublicKey, nil
+}
+
+// verifyResponseKey verifies the signature against the response's own key, of either type.
+func verifyResponseKey(ctx context.Context, cfg *verifyConfig, msg *Nep413Message, res *Nep413SignatureResponse) (*VerifyResult, error) {
+	keyType, err := KeyTypeOf(res.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.checkKeyType(keyType); err != nil {
+		return nil, err
+	}
+
+	match, err := cfg.responseKeyMatcher(res, keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := verifyMatch(ctx, cfg, msg, res, match)
+	if err == ErrNoMatchingKey {
+		return nil, ErrVerificationFailed
+	}
+
+	return result, err
+}
+
+// responseKeyMatcher parses the response's public key of the given type, returning the
+// function that verifies signatures against it.
+func (c *verifyConfig) responseKeyMatcher(res *Nep413SignatureResponse, keyType KeyType) (func(*Nep413Message) (*signatureMatch, error), error) {
+	if keyType == KeyTypeSECP256K1 {
+		publicKey, err := ParseSecp256k1PublicKey(res.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(msg *Nep413Message) (*signatureMatch, error) {
+			return c.matchSecp256k1(msg, res, publicKey)
+		}, nil
+	}
+
+	publicKey, err := res.PubKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return func(msg *Nep413Message) (*signatureMatch, error) {
+		return c.matchKey(msg, res, []ed25519.PublicKey{publicKey})
+	}, nil
+}
+
+// verify applies the policy in cfg, and verifies the signature against each of the keys.
+func verify(ctx context.Context, cfg *verifyConfig, msg *Nep413Message, res *Nep413SignatureResponse, keys []ed25519.PublicKey) (*VerifyResult, error) {
+	if err := cfg.checkKeyType(KeyTypeED25519); err != nil {
+		return nil, err
+	}
+
+	return verifyMatch(ctx, cfg, msg, res, func(msg *Nep413Message) (*signatureMatch, error) {
+		return cfg.matchKey(msg, res, keys)
+	})
+}
+
+// verifyMatch applies the policy in cfg, using match to verify the signature.
+// All verification paths go through verifyMatch, so the result is populated consistently.
+func verifyMatch(ctx context.Context, cfg *verifyConfig, msg *Nep413Message, res *Nep413SignatureResponse, match func(*Nep413Message) (*signatureMatch, error)) (*VerifyResult, error) {
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	matchedRecipient, err := cfg.checkRecipient(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.checkMessage(msg); err != nil {
+		return nil, err
+	}
+
+	original := msg
+	matchedKey, err := cfg.matchSignature(msg, match)
+	if err != nil {
+		return nil, err
+	}
+	matched := matchedKey.key
+	msg = matchedKey.msg
+
+	// weak keys and implicit accounts only exist for ed25519 keys
+	derived := false
+	if matched != nil {
+		if cfg.rejectWeakKeys && isWeakPublicKey(matched) {
+			return nil, ErrWeakPublicKey
+		}
+
+		res, derived = cfg.deriveAccount(res, matched)
+	}
+
+	if err := cfg.checkResponse(res); err != nil {
+		return nil, err
+	}
+
+	accessKey, err := cfg.checkMatchedAccessKey(ctx, res, matchedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.allow(res, matchedKey, derived || accessKey != nil); err != nil {
+		return nil, err
+	}
+
+	// the nonce is only consumed once everything else has passed,
+	// so that invalid requests cannot burn nonces
+	if err := contextErr(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.consumeNonce(ctx, msg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.consumeSignature(ctx, matchedKey.signature); err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{
+		AccountId:        res.AccountId,
+		PublicKey:        matched,
+		KeyType:          KeyTypeED25519,
+		Nonce:            msg.Nonce,
+		Recipient:        msg.Recipient,
+		PayloadHash:      matchedKey.hash,
+		MatchedRecipient: matchedRecipient,
+		AccessKey:        accessKey,
+		VerifiedAt:       cfg.now(),
+		AccountIdDerived: derived,
+		RecipientTrimmed: msg.Recipient != original.Recipient,
+	}
+	result.NearKey = matchedKey.nearKey()
+	if matchedKey.secp256k1Key != nil {
+		result.KeyType = KeyTypeSECP256K1
+		result.Secp256k1PublicKey = matchedKey.secp256k1Key
+	}
+
+	return result, nil
+}
+
+// signatureMatch is a key that validated a response's signature.
+type signatureMatch struct {
+	// key is the matching key, unless it is a secp256k1 key
+	key ed25519.PublicKey
+	// secp256k1Key is the matching key, if it is a secp256k1 key
+	secp256k1Key *secp256k1.PublicKey
+	// signature is the decoded signature
+	signature []byte
+	// hash is the sha256 hash of the payload
+	hash [32]byte
+	// msg is the message as signed, which differs from the given message if its
+	// recipient was trimmed (see TryTrimmedRecipient)
+	msg *Nep413Message
+}
+
+// nearKey returns the matching key in NEAR's format.
+func (m *signatureMatch) nearKey() string {
+	if m.secp256k1Key != nil {
+		return FormatSecp256k1PublicKey(m.secp256k1Key)
+	}
+
+	return FormatPublicKey(m.key)
+}
+
+// matchSignature verifies the signature of msg with match. With TryTrimmedRecipient, the
+// message with a whitespace trimmed recipient is tried next.
+func (c *verifyConfig) matchSignature(msg *Nep413Message, matchMessage func(*Nep413Message) (*signatureMatch, error)) (*signatureMatch, error) {
+	match, err := matchMessage(msg)
+	if err != ErrNoMatchingKey || !c.tryTrimmedRecipient {
+		return match, err
+	}
+
+	trimmed := strings.TrimSpace(msg.Recipient)
+	if trimmed == msg.Recipient {
+		return nil, err
+	}
+
+	// the caller's message is not modified
+	withTrimmed := *msg
+	withTrimmed.Recipient = trimmed
+	return matchMessage(&withTrimmed)
+}
+
+// matchKey verifies the signature of msg against each of the keys, returning the first key
+// that validates it.
+func (c *verifyConfig) matchKey(msg *Nep413Message, res *Nep413SignatureResponse, keys []ed25519.PublicKey) (*signatureMatch, error) {
 	// decode the signature
-	decodedSignature, err := base64.StdEncoding.DecodeString(res.Signature)
+	decodedSignature, err := c.decodeSignature(res.Signature, KeyTypeED25519)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// serialize payload
-	// we dereference pointer since go-borsch is bugged
-	// and does not correctly handle pointers
-	serializedPayload, err := borsch.Serialize(*msg)
+	payload, err := c.payload(msg)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	hash := sha256.Sum256(payload)
+	signedBytes := c.signedBytes(payload, hash)
 
-	fmt.Println("serializedPayload", serializedPayload)
+	for _, key := range keys {
+		// ed25519.Verify panics on malformed keys
+		if len(key) != ed25519.PublicKeySize {
+			continue
+		}
 
-	// hash the payload
-	hashedPayload := sha256.Sum256(serializedPayload)
+		if ed25519.Verify(key, signedBytes, decodedSignature) {
+			return &signatureMatch{key: key, signature: decodedSignature, hash: hash, msg: msg}, nil
+		}
+	}
+
+	return nil, ErrNoMatchingKey
+}
 
-	if !ed25519.Verify(publicKey, hashedPayload[:], decodedSignature) {
-		return errors.New("signature verification failed")
+// SerializePayload returns the exact bytes an NEP-413 wallet hashes and signs for msg: the
+// borsch serialization of the message with its tag set to Nep413Tag, e.g. to compare against
+// the payload near-api-js produces. The message is left untouched.
+func SerializePayload(msg *Nep413Message) ([]byte, error) {
+	return serializePayload(msg)
+}
+
+// HashPayload returns the sha256 hash of SerializePayload(msg), which is the digest an
+// NEP-413 signature is over, e.g. to have it signed by an HSM (see SignPayload and VerifyHash).
+func HashPayload(msg *Nep413Message) ([32]byte, error) {
+	payload, err := SerializePayload(msg)
+	if err != nil {
+		return [32]byte{}, err
 	}
 
-	return nil
+	return sha256.Sum256(payload), nil
+}
+
+// serializePayload returns the borsch serialization of the message with the NEP-413 tag.
+// The message is not modified, so it can be shared by concurrent verifications.
+func serializePayload(msg *Nep413Message) ([]byte, error) {
+	return serializeWithTag(msg, Nep413Tag)
+}
+
+// serializeWithTag returns the borsch serialization of the message with the given tag,
+// without modifying the message.
+func serializeWithTag(msg *Nep413Message, tag uint32) ([]byte, error) {
+	tagged := *msg
+	tagged.Tag = tag
+
+	return encodePayload(&tagged)
+}
+
+// encodePayload returns the borsch serialization of the message, with its tag as is.
+func encodePayload(msg *Nep413Message) ([]byte, error) {
+	// serialize payload
+	// we dereference pointer since go-borsch is bugged
+	// and does not correctly handle pointers
+	return borsch.Serialize(*msg)
 }
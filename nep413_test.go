@@ -1,11 +1,72 @@
 package nep413_test
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"sync"
 	"testing"
 
 	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+	borsch "github.com/near/borsh-go"
 )
 
+// testVector returns the message and response from a real NEAR wallet signature.
+func testVector() (*nep413.Nep413Message, *nep413.Nep413SignatureResponse) {
+	msg := &nep413.Nep413Message{
+		Message:   "idOS authentication",
+		Recipient: "idos.network",
+		Nonce:     [32]byte{5, 233, 107, 175, 203, 182, 15, 111, 97, 146, 18, 10, 118, 80, 180, 9, 186, 39, 255, 93, 36, 218, 196, 25, 72, 177, 237, 28, 173, 75, 17, 31},
+	}
+
+	res := &nep413.Nep413SignatureResponse{
+		Signature: "Ni+rXvOtyzRr7X+qtvQ9+iJUu2e8L/e6cPjSzOYr+6W22chVnptTW0QqTUhFgKUbgPwd2tTcfB1D9Q+0Xb+sBg==",
+		PublicKey: "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg",
+	}
+
+	return msg, res
+}
+
+// testPayload returns the tagged borsch payload for msg.
+func testPayload(t testing.TB, msg *nep413.Nep413Message) []byte {
+	payload := *msg
+	payload.Tag = nep413.Nep413Tag
+
+	serialized, err := borsch.Serialize(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return serialized
+}
+
+// testSign signs msg with a new key, returning the response. If the signed bytes
+// are not given, the sha256 of the payload is signed, as a wallet would.
+func testSign(t *testing.T, msg *nep413.Nep413Message, signed ...[]byte) (*nep413.Nep413SignatureResponse, ed25519.PrivateKey) {
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data []byte
+	if len(signed) > 0 {
+		data = signed[0]
+	} else {
+		hash := sha256.Sum256(testPayload(t, msg))
+		data = hash[:]
+	}
+
+	return &nep413.Nep413SignatureResponse{
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, data)),
+		PublicKey: "ed25519:" + base58.Encode(priv.Public().(ed25519.PublicKey)),
+	}, priv
+}
+
 func Test_Nep413(t *testing.T) {
 	msg := nep413.Nep413Message{
 		Message:   "idOS authentication",
@@ -24,3 +85,348 @@ func Test_Nep413(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func Test_VerifyWithCandidateKeys(t *testing.T) {
+	msg, res := testVector()
+
+	walletKey, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matched, err := nep413.VerifyWithCandidateKeys(msg, res, []ed25519.PublicKey{otherKey, walletKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(matched, walletKey) {
+		t.Fatalf("expected wallet key to match, got %x", matched)
+	}
+
+	_, err = nep413.VerifyWithCandidateKeys(msg, res, []ed25519.PublicKey{otherKey, {1, 2, 3}})
+	if !errors.Is(err, nep413.ErrNoMatchingKey) {
+		t.Fatalf("expected ErrNoMatchingKey, got %v", err)
+	}
+}
+
+func Test_ResponseCBOR(t *testing.T) {
+	_, res := testVector()
+	res.AccountId = "idos.near"
+
+	data, err := res.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys use the json names
+	if !bytes.Contains(data, []byte("publicKey")) {
+		t.Fatalf("expected publicKey key in %x", data)
+	}
+
+	var decoded nep413.Nep413SignatureResponse
+	if err := decoded.UnmarshalCBOR(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != *res {
+		t.Fatalf("expected %+v, got %+v", *res, decoded)
+	}
+}
+
+// gob encodes the response with its MarshalBinary and UnmarshalBinary methods
+func Test_ResponseGob(t *testing.T) {
+	_, res := testVector()
+	res.AccountId = "idos.near"
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(res); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded nep413.Nep413SignatureResponse
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded != *res {
+		t.Fatalf("expected %+v, got %+v", *res, decoded)
+	}
+}
+
+func Test_VerifyHash(t *testing.T) {
+	msg, res := testVector()
+
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256(testPayload(t, msg))
+	if err := nep413.VerifyHash(hash, sig, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	hash[0]++
+	if err := nep413.VerifyHash(hash, sig, pub); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	if err := nep413.VerifyHash(hash, sig[:10], pub); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	if err := nep413.VerifyHash(hash, sig, pub[:10]); err == nil {
+		t.Fatal("expected error for short public key")
+	}
+}
+
+func Test_VerifyRawPayload(t *testing.T) {
+	msg, res := testVector()
+
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := testPayload(t, msg)
+	if err := nep413.VerifyRawPayload(payload, sig, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.VerifyRawPayload(payload, sig[:10], pub); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	if err := nep413.VerifyRawPayload(payload[:len(payload)-1], sig, pub); !errors.Is(err, nep413.ErrMalformedInput) {
+		t.Fatalf("expected ErrMalformedInput, got %v", err)
+	}
+
+	untagged := *msg
+	untagged.Tag = 1
+	encoded, err := borsch.Serialize(untagged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.VerifyRawPayload(encoded, sig, pub); !errors.Is(err, nep413.ErrInvalidTag) {
+		t.Fatalf("expected ErrInvalidTag, got %v", err)
+	}
+}
+
+func Test_VerifyWithKey(t *testing.T) {
+	msg, res := testVector()
+
+	walletKey, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an attacker swaps in their own key and signature
+	forged, _ := testSign(t, msg)
+	if err := nep413.Verify(msg, forged); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.VerifyWithKey(msg, forged, walletKey); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	if err := nep413.VerifyWithKey(msg, res, walletKey); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Nep413Tag(t *testing.T) {
+	if nep413.Nep413Tag != 2147484061 {
+		t.Fatalf("expected tag 2147484061, got %d", nep413.Nep413Tag)
+	}
+}
+
+func Test_SignatureEncodings(t *testing.T) {
+	msg, res := testVector()
+	signature, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefixed := *res
+	prefixed.Signature = "ed25519:" + base58.Encode(signature)
+	if err := nep413.Verify(msg, &prefixed); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := map[string]string{
+		"wrong prefix":          "secp256k1:" + base58.Encode(signature),
+		"invalid base58":        "ed25519:0OIl",
+		"too short base58":      "ed25519:" + base58.Encode(signature[:32]),
+		"too short base64":      base64.StdEncoding.EncodeToString(signature[:63]),
+		"base58 without prefix": base58.Encode(signature),
+	}
+	for name, encoded := range invalid {
+		bad := *res
+		bad.Signature = encoded
+		err := nep413.Verify(msg, &bad)
+		if !errors.Is(err, nep413.ErrInvalidSignatureEncoding) || !errors.Is(err, nep413.ErrVerificationFailed) {
+			t.Errorf("%s: expected ErrInvalidSignatureEncoding, got %v", name, err)
+		}
+	}
+}
+
+func Test_RequestFingerprint(t *testing.T) {
+	msg, _ := testVector()
+	fingerprint := nep413.RequestFingerprint(msg)
+	if len(fingerprint) != 64 {
+		t.Fatalf("expected a hex sha256 hash, got %q", fingerprint)
+	}
+
+	// the tag is not part of the fingerprint
+	untagged := *msg
+	untagged.Tag = 0
+	if nep413.RequestFingerprint(&untagged) != fingerprint {
+		t.Fatal("expected the fingerprint not to depend on the tag")
+	}
+
+	callbackURL := "https://idos.network/callback"
+	changes := map[string]func(*nep413.Nep413Message){
+		"message":      func(m *nep413.Nep413Message) { m.Message += "!" },
+		"nonce":        func(m *nep413.Nep413Message) { m.Nonce[31]++ },
+		"recipient":    func(m *nep413.Nep413Message) { m.Recipient = "other.near" },
+		"callback url": func(m *nep413.Nep413Message) { m.CallbackUrl = &callbackURL },
+		// the fields are length prefixed, so moving bytes between them changes the fingerprint
+		"field boundary": func(m *nep413.Nep413Message) {
+			m.Message, m.Recipient = m.Message+m.Recipient[:1], m.Recipient[1:]
+		},
+	}
+	for name, change := range changes {
+		changed := *msg
+		change(&changed)
+		if nep413.RequestFingerprint(&changed) == fingerprint {
+			t.Errorf("%s: expected the fingerprint to change", name)
+		}
+	}
+}
+
+func Test_ResponsesEqual(t *testing.T) {
+	_, res := testVector()
+	res.AccountId = "idos.near"
+	signature, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := map[string]func(*nep413.Nep413SignatureResponse){
+		"identical":           func(r *nep413.Nep413SignatureResponse) {},
+		"unpadded signature":  func(r *nep413.Nep413SignatureResponse) { r.Signature = base64.RawStdEncoding.EncodeToString(signature) },
+		"base58 signature":    func(r *nep413.Nep413SignatureResponse) { r.Signature = "ed25519:" + base58.Encode(signature) },
+		"hex key":             func(r *nep413.Nep413SignatureResponse) { r.PublicKey = "ed25519:" + hex.EncodeToString(pub) },
+		"account id case":     func(r *nep413.Nep413SignatureResponse) { r.AccountId = "IDOS.near" },
+		"state is not signed": func(r *nep413.Nep413SignatureResponse) { r.State = "other" },
+	}
+	for name, change := range same {
+		other := *res
+		change(&other)
+		if !nep413.ResponsesEqual(res, &other) {
+			t.Errorf("%s: expected the responses to be equal", name)
+		}
+	}
+
+	different := map[string]func(*nep413.Nep413SignatureResponse){
+		"account id": func(r *nep413.Nep413SignatureResponse) { r.AccountId = "other.near" },
+		"signature": func(r *nep413.Nep413SignatureResponse) {
+			r.Signature = base64.StdEncoding.EncodeToString(append(signature[:63:63], signature[63]+1))
+		},
+		"key": func(r *nep413.Nep413SignatureResponse) {
+			r.PublicKey = nep413.FormatPublicKey(make(ed25519.PublicKey, 32))
+		},
+		"malformed signature": func(r *nep413.Nep413SignatureResponse) { r.Signature = "!" },
+	}
+	for name, change := range different {
+		other := *res
+		change(&other)
+		if nep413.ResponsesEqual(res, &other) {
+			t.Errorf("%s: expected the responses to differ", name)
+		}
+	}
+
+	if nep413.ResponsesEqual(res, nil) || nep413.ResponsesEqual(nil, res) || nep413.ResponsesEqual(nil, nil) {
+		t.Fatal("expected nil responses to never be equal")
+	}
+}
+
+func Test_SerializePayload(t *testing.T) {
+	msg, res := testVector()
+	msg.Tag = 0
+
+	payload, err := nep413.SerializePayload(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Tag != 0 {
+		t.Fatal("expected the message to be left untouched")
+	}
+	if !bytes.Equal(payload, testPayload(t, msg)) {
+		t.Fatalf("unexpected payload %x", payload)
+	}
+
+	hash, err := nep413.HashPayload(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != sha256.Sum256(payload) {
+		t.Fatal("expected the hash to be the sha256 of the payload")
+	}
+
+	// the wallet signed this hash
+	sig, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.VerifyHash(hash, sig, pub); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_VerifyLeavesMessageUntouched(t *testing.T) {
+	msg, res := testVector()
+	msg.Tag = 0
+	original := *msg
+
+	// the message is shared by concurrent verifications, which go test -race checks
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := nep413.Verify(msg, res); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if *msg != original {
+		t.Fatalf("expected the message to be left untouched, got %+v", msg)
+	}
+}
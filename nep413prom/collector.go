@@ -0,0 +1,72 @@
+// Package nep413prom exposes the Stats of an nep413.Verifier as Prometheus metrics.
+// It is a separate module, so that users of nep413 who do not use Prometheus do not
+// depend on it.
+package nep413prom
+
+import (
+	"github.com/brennanjl/nep413"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	verificationsDesc = prometheus.NewDesc(
+		"nep413_verifications_total",
+		"Number of NEP-413 verifications, by outcome.",
+		[]string{"outcome"}, nil,
+	)
+	failuresDesc = prometheus.NewDesc(
+		"nep413_verification_failures_total",
+		"Number of failed NEP-413 verifications, by reason, as labeled by nep413.FailureReason.",
+		[]string{"reason"}, nil,
+	)
+	latencyDesc = prometheus.NewDesc(
+		"nep413_verification_duration_seconds",
+		"Duration of NEP-413 verifications, including access key lookups.",
+		nil, nil,
+	)
+)
+
+// collector reads a verifier's Stats whenever it is collected.
+type collector struct {
+	verifier *nep413.Verifier
+}
+
+// PrometheusCollector returns a collector of the verifier's Stats, e.g. to register with
+// prometheus.MustRegister. It exposes:
+//   - nep413_verifications_total, counting verifications by outcome ("success" or "failure")
+//   - nep413_verification_failures_total, counting failures by reason (see nep413.FailureReason)
+//   - nep413_verification_duration_seconds, a histogram of verification latencies, with the
+//     buckets of nep413.LatencyBuckets
+//
+// The verifier's counters are read at collection time, so collecting is cheap, and adds no
+// work to verification. Register one collector per verifier, or add const labels to tell
+// verifiers apart with prometheus.WrapRegistererWith.
+func PrometheusCollector(v *nep413.Verifier) prometheus.Collector {
+	return &collector{verifier: v}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- verificationsDesc
+	ch <- failuresDesc
+	ch <- latencyDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.verifier.Stats.Snapshot()
+
+	ch <- prometheus.MustNewConstMetric(verificationsDesc, prometheus.CounterValue, float64(stats.Succeeded), "success")
+	ch <- prometheus.MustNewConstMetric(verificationsDesc, prometheus.CounterValue, float64(stats.Failed), "failure")
+
+	for reason, count := range stats.FailedByReason {
+		ch <- prometheus.MustNewConstMetric(failuresDesc, prometheus.CounterValue, float64(count), reason)
+	}
+
+	buckets := make(map[float64]uint64, len(stats.LatencyBuckets))
+	for i, count := range stats.LatencyBuckets {
+		buckets[nep413.LatencyBuckets[i].Seconds()] = count
+	}
+	// the count must be read with the buckets, or it may fall behind them during verifications
+	ch <- prometheus.MustNewConstHistogram(latencyDesc, stats.LatencyCount, stats.LatencySum.Seconds(), buckets)
+}
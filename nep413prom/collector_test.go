@@ -0,0 +1,62 @@
+package nep413prom_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	"github.com/brennanjl/nep413/nep413prom"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_PrometheusCollector(t *testing.T) {
+	v := nep413.NewVerifier()
+
+	msg := &nep413.Nep413Message{Message: "idOS authentication", Recipient: "idos.network"}
+	res := nep413.SignForTest([32]byte{7}, msg)
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := *msg
+	tampered.Message = "tampered"
+	if _, err := v.Verify(&tampered, res); err == nil {
+		t.Fatal("expected the tampered message to fail")
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(nep413prom.PrometheusCollector(v))
+
+	expected := `
+# HELP nep413_verification_failures_total Number of failed NEP-413 verifications, by reason, as labeled by nep413.FailureReason.
+# TYPE nep413_verification_failures_total counter
+nep413_verification_failures_total{reason="invalid_signature"} 1
+# HELP nep413_verifications_total Number of NEP-413 verifications, by outcome.
+# TYPE nep413_verifications_total counter
+nep413_verifications_total{outcome="failure"} 1
+nep413_verifications_total{outcome="success"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "nep413_verifications_total", "nep413_verification_failures_total"); err != nil {
+		t.Fatal(err)
+	}
+
+	if count := testutil.CollectAndCount(nep413prom.PrometheusCollector(v), "nep413_verification_duration_seconds"); count != 1 {
+		t.Fatalf("expected a latency histogram, got %d metrics", count)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "nep413_verification_duration_seconds" {
+			continue
+		}
+		histogram := family.GetMetric()[0].GetHistogram()
+		buckets := histogram.GetBucket()
+		if histogram.GetSampleCount() != 2 || buckets[len(buckets)-1].GetCumulativeCount() > histogram.GetSampleCount() {
+			t.Fatalf("unexpected latency histogram %v", histogram)
+		}
+	}
+}
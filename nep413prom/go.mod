@@ -0,0 +1,30 @@
+module github.com/brennanjl/nep413/nep413prom
+
+go 1.21.0
+
+require (
+	github.com/brennanjl/nep413 v0.0.0
+	github.com/prometheus/client_golang v1.19.1
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.4.1 // indirect
+	github.com/fxamacker/cbor/v2 v2.7.0 // indirect
+	github.com/mr-tron/base58 v1.2.0 // indirect
+	github.com/near/borsh-go v0.3.1 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.15.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
+
+// the collector needs Stats, which no tagged release of nep413 has yet, so it is built
+// against the package in this repository until one is
+replace github.com/brennanjl/nep413 => ../
@@ -0,0 +1,192 @@
+package nep413
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNonceExpired is returned when a timestamped nonce is older than the allowed age.
+	ErrNonceExpired = errors.New("nonce has expired")
+	// ErrNonceInFuture is returned when a timestamped nonce is further in the future than the allowed skew.
+	ErrNonceInFuture = errors.New("nonce timestamp is in the future")
+	// ErrNonceNotTimestamped is returned when a freshness check requires a timestamped nonce, but the nonce is random.
+	ErrNonceNotTimestamped = errors.New("nonce is not timestamped")
+	// ErrNonceMismatch is returned when the signed nonce is not the one expected for the request.
+	ErrNonceMismatch = errors.New("nonce does not match the expected nonce")
+)
+
+// timestampedNoncePrefix marks a nonce created by NewTimestampedNonce.
+var timestampedNoncePrefix = [4]byte{'N', '4', '1', '3'}
+
+// NonceGenerator creates nonces from a configurable random source and clock, e.g. to make
+// tests deterministic. The zero value uses crypto/rand and time.Now, like NewNonce and
+// NewTimestampedNonce. Production code should use the defaults: nonces from a predictable
+// source can be signed for in advance.
+type NonceGenerator struct {
+	// Rand, if set, is the source of the random bytes instead of crypto/rand
+	Rand io.Reader
+	// NowFunc, if set, is the clock used instead of time.Now
+	NowFunc func() time.Time
+}
+
+// NewNonce returns a random 32 byte nonce.
+func (g *NonceGenerator) NewNonce() ([32]byte, error) {
+	var nonce [32]byte
+	err := g.read(nonce[:])
+	return nonce, err
+}
+
+// NewTimestampedNonce returns a nonce that embeds the current time, so that its
+// age can be checked with WithNonceFreshness.
+// The nonce is laid out as a 4 byte marker, an 8 byte big endian unix timestamp in
+// milliseconds, and 20 random bytes.
+func (g *NonceGenerator) NewTimestampedNonce() ([32]byte, error) {
+	now := time.Now
+	if g.NowFunc != nil {
+		now = g.NowFunc
+	}
+
+	var nonce [32]byte
+	copy(nonce[:4], timestampedNoncePrefix[:])
+	binary.BigEndian.PutUint64(nonce[4:12], uint64(now().UnixMilli()))
+	err := g.read(nonce[12:])
+	return nonce, err
+}
+
+// read fills b from the random source.
+func (g *NonceGenerator) read(b []byte) error {
+	if g.Rand == nil {
+		_, err := rand.Read(b)
+		return err
+	}
+
+	_, err := io.ReadFull(g.Rand, b)
+	return err
+}
+
+// NewNonce returns a random 32 byte nonce.
+func NewNonce() ([32]byte, error) {
+	return (&NonceGenerator{}).NewNonce()
+}
+
+// NewTimestampedNonce returns a nonce that embeds the current time, so that its
+// age can be checked with WithNonceFreshness. See NonceGenerator.NewTimestampedNonce
+// for its layout.
+func NewTimestampedNonce() ([32]byte, error) {
+	return (&NonceGenerator{}).NewTimestampedNonce()
+}
+
+// NonceTimestamp returns the time embedded in a nonce created by NewTimestampedNonce.
+// It returns false if the nonce is not timestamped.
+func NonceTimestamp(nonce [32]byte) (time.Time, bool) {
+	if [4]byte(nonce[:4]) != timestampedNoncePrefix {
+		return time.Time{}, false
+	}
+
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(nonce[4:12]))), true
+}
+
+// WithNonceFreshness requires the signed nonce to be timestamped (see NewTimestampedNonce),
+// and its timestamp ts to satisfy
+//
+//	now - maxAge <= ts <= now + futureSkew
+//
+// failing with ErrNonceExpired or ErrNonceInFuture respectively. futureSkew tolerates
+// wallets and servers with slightly different clocks. Random nonces fail with
+// ErrNonceNotTimestamped, since their age cannot be known. now is time.Now, unless
+// changed with WithClock or Verifier.NowFunc.
+// It is independent of RejectFutureNonce: if both are given, the smaller skew applies.
+func WithNonceFreshness(maxAge, futureSkew time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.nonceMaxAge = &maxAge
+		c.nonceFreshnessSkew = &futureSkew
+	}
+}
+
+// RejectFutureNonce fails verification with ErrNonceInFuture if the signed nonce is
+// timestamped more than skew in the future, which signals clock tampering. Unlike
+// WithNonceFreshness, old nonces (e.g. from offline signing) are accepted, and random
+// nonces are unaffected. If both are given, the smaller skew applies.
+func RejectFutureNonce(skew time.Duration) VerifyOption {
+	return func(c *verifyConfig) {
+		c.nonceFutureSkew = &skew
+	}
+}
+
+// futureSkew returns the tighter of the future skews of WithNonceFreshness and
+// RejectFutureNonce, or nil if neither is set.
+func (c *verifyConfig) futureSkew() *time.Duration {
+	if c.nonceFreshnessSkew == nil || (c.nonceFutureSkew != nil && *c.nonceFutureSkew < *c.nonceFreshnessSkew) {
+		return c.nonceFutureSkew
+	}
+
+	return c.nonceFreshnessSkew
+}
+
+// NonceFromRequestID derives a nonce from a request id, as the HMAC-SHA256 of the id keyed by
+// salt, so that a stateless server can bind a signature to the request without storing its
+// nonce: WithRequestIDNonce recomputes the nonce from the id when verifying.
+//
+// Unlike a random nonce, the nonce is only as unique as the request id: a reused id yields the
+// same nonce, so a signature for it can be replayed. Request ids must be unique, and, since this
+// gives no replay protection by itself, should be bound to a short validity window by the server.
+// salt should be a secret of at least 32 random bytes, so that clients cannot compute the nonce
+// of a request id they were not issued. Distinct ids collide with negligible probability.
+func NonceFromRequestID(requestID string, salt []byte) [32]byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(requestID))
+
+	var nonce [32]byte
+	copy(nonce[:], mac.Sum(nil))
+	return nonce
+}
+
+// WithRequestIDNonce fails verification with ErrNonceMismatch unless the signed nonce is
+// NonceFromRequestID(requestID, salt).
+func WithRequestIDNonce(requestID string, salt []byte) VerifyOption {
+	return WithExpectedNonce(NonceFromRequestID(requestID, salt))
+}
+
+// WithExpectedNonce fails verification with ErrNonceMismatch unless the signed nonce is
+// exactly nonce, e.g. one issued out-of-band for a single-shot flow. Combined with
+// WithExpectedRecipient, the signature is only valid for that nonce and recipient.
+// It does not prevent the signature from being replayed; pair it with a nonce store, or
+// discard the nonce once used, for that. It replaces WithRequestIDNonce, and vice versa.
+func WithExpectedNonce(nonce [32]byte) VerifyOption {
+	return func(c *verifyConfig) {
+		c.expectedNonce = &nonce
+	}
+}
+
+// checkNonce applies the nonce policy to the signed nonce: the expected nonce, and freshness.
+func (c *verifyConfig) checkNonce(msg *Nep413Message) error {
+	if c.expectedNonce != nil && subtle.ConstantTimeCompare(msg.Nonce[:], c.expectedNonce[:]) != 1 {
+		return ErrNonceMismatch
+	}
+
+	ts, ok := NonceTimestamp(msg.Nonce)
+	if c.nonceMaxAge != nil && !ok {
+		return ErrNonceNotTimestamped
+	}
+	if !ok {
+		return nil
+	}
+
+	now := c.now()
+	if c.nonceMaxAge != nil && ts.Before(now.Add(-*c.nonceMaxAge)) {
+		return ErrNonceExpired
+	}
+
+	if skew := c.futureSkew(); skew != nil && ts.After(now.Add(*skew)) {
+		return ErrNonceInFuture
+	}
+
+	return nil
+}
@@ -0,0 +1,215 @@
+package nep413
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNonceReused is returned when a nonce has already been used for the recipient.
+var ErrNonceReused = errors.New("nonce has already been used")
+
+// NonceStore records consumed nonces, to prevent a signature from being replayed.
+// Nonces are scoped to a recipient, so two applications can use the same nonce.
+// Implementations must be safe for concurrent use.
+type NonceStore interface {
+	// Seen atomically marks the nonce as used for the recipient, and reports
+	// whether it had already been used.
+	Seen(ctx context.Context, recipient string, nonce [32]byte) (bool, error)
+	// Has reports whether the nonce has been used for the recipient, without marking it.
+	// It is a read-only check, e.g. to short-circuit duplicate submissions; only Seen
+	// provides single-use guarantees.
+	Has(ctx context.Context, recipient string, nonce [32]byte) (bool, error)
+}
+
+// NonceReserver is implemented by NonceStores that can reserve a nonce for a ttl of the
+// caller's choosing, e.g. for the lifetime of an issued challenge rather than the store's
+// ttl. All of the package's stores implement it.
+type NonceReserver interface {
+	// Reserve atomically marks the nonce as used for the recipient for ttl if it is unused,
+	// and reports whether it did. Of concurrent reservations of the same nonce, exactly one
+	// succeeds. It is the inverse of Seen, with an explicit ttl.
+	Reserve(ctx context.Context, recipient string, nonce [32]byte, ttl time.Duration) (reserved bool, err error)
+}
+
+// WithNonceStore fails verification with ErrNonceReused if the signed nonce has
+// already been used for the signed recipient. The nonce is only consumed once
+// all other checks have passed.
+func WithNonceStore(store NonceStore) VerifyOption {
+	return func(c *verifyConfig) {
+		c.nonceStore = store
+	}
+}
+
+// consumeNonce marks the signed nonce as used in the nonce store, if any.
+func (c *verifyConfig) consumeNonce(ctx context.Context, msg *Nep413Message) error {
+	if c.nonceStore == nil {
+		return nil
+	}
+
+	seen, err := c.nonceStore.Seen(ctx, msg.Recipient, msg.Nonce)
+	if err != nil {
+		return err
+	}
+
+	if seen {
+		return ErrNonceReused
+	}
+
+	return nil
+}
+
+// nonceKey identifies a nonce in a store.
+type nonceKey struct {
+	recipient string
+	nonce     [32]byte
+}
+
+// MemoryNonceStore is an in-memory NonceStore, guarded by a mutex.
+// Nonces are forgotten after the store's ttl, so the ttl must be at least as long
+// as signatures are otherwise accepted for (e.g. the nonce freshness window).
+type MemoryNonceStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	expiries  map[nonceKey]time.Time
+	lastPrune time.Time
+}
+
+var (
+	_ NonceStore    = (*MemoryNonceStore)(nil)
+	_ NonceReserver = (*MemoryNonceStore)(nil)
+)
+
+// NewMemoryNonceStore creates an in-memory nonce store that remembers nonces for ttl.
+func NewMemoryNonceStore(ttl time.Duration) *MemoryNonceStore {
+	return &MemoryNonceStore{
+		ttl:      ttl,
+		expiries: make(map[nonceKey]time.Time),
+	}
+}
+
+// Seen implements NonceStore.
+func (m *MemoryNonceStore) Seen(ctx context.Context, recipient string, nonce [32]byte) (bool, error) {
+	reserved, err := m.Reserve(ctx, recipient, nonce, m.ttl)
+	return !reserved, err
+}
+
+// Reserve implements NonceReserver.
+func (m *MemoryNonceStore) Reserve(_ context.Context, recipient string, nonce [32]byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.prune(now)
+
+	key := nonceKey{recipient, nonce}
+	if expiry, ok := m.expiries[key]; ok && now.Before(expiry) {
+		return false, nil
+	}
+
+	m.expiries[key] = now.Add(ttl)
+	return true, nil
+}
+
+// Has implements NonceStore.
+func (m *MemoryNonceStore) Has(_ context.Context, recipient string, nonce [32]byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiry, ok := m.expiries[nonceKey{recipient, nonce}]
+	return ok && time.Now().Before(expiry), nil
+}
+
+// prune removes expired nonces. It runs at most once per ttl.
+func (m *MemoryNonceStore) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < m.ttl {
+		return
+	}
+	m.lastPrune = now
+
+	for key, expiry := range m.expiries {
+		if !now.Before(expiry) {
+			delete(m.expiries, key)
+		}
+	}
+}
+
+// SyncMapNonceStore is an in-memory NonceStore backed by a sync.Map.
+// It has less lock contention than MemoryNonceStore for read-heavy workloads,
+// where most calls see nonces that are already used.
+type SyncMapNonceStore struct {
+	ttl time.Duration
+	// expiries maps a nonceKey to its expiry, in unix nanoseconds
+	expiries  sync.Map
+	lastPrune atomic.Int64
+}
+
+var (
+	_ NonceStore    = (*SyncMapNonceStore)(nil)
+	_ NonceReserver = (*SyncMapNonceStore)(nil)
+)
+
+// NewSyncMapNonceStore creates a sync.Map backed nonce store that remembers nonces for ttl.
+func NewSyncMapNonceStore(ttl time.Duration) *SyncMapNonceStore {
+	return &SyncMapNonceStore{ttl: ttl}
+}
+
+// Seen implements NonceStore.
+func (m *SyncMapNonceStore) Seen(ctx context.Context, recipient string, nonce [32]byte) (bool, error) {
+	reserved, err := m.Reserve(ctx, recipient, nonce, m.ttl)
+	return !reserved, err
+}
+
+// Reserve implements NonceReserver.
+func (m *SyncMapNonceStore) Reserve(_ context.Context, recipient string, nonce [32]byte, ttl time.Duration) (bool, error) {
+	now := time.Now().UnixNano()
+	m.prune(now)
+
+	key := nonceKey{recipient, nonce}
+
+	// fast path for used nonces, which does not allocate
+	if actual, ok := m.expiries.Load(key); ok && now < actual.(int64) {
+		return false, nil
+	}
+
+	expiry := now + int64(ttl)
+	for {
+		actual, loaded := m.expiries.LoadOrStore(key, expiry)
+		if !loaded {
+			return true, nil
+		}
+
+		if now < actual.(int64) {
+			return false, nil
+		}
+
+		// the nonce expired, so it can be reused if no other call claims it first
+		if m.expiries.CompareAndSwap(key, actual, expiry) {
+			return true, nil
+		}
+	}
+}
+
+// Has implements NonceStore.
+func (m *SyncMapNonceStore) Has(_ context.Context, recipient string, nonce [32]byte) (bool, error) {
+	expiry, ok := m.expiries.Load(nonceKey{recipient, nonce})
+	return ok && time.Now().UnixNano() < expiry.(int64), nil
+}
+
+// prune removes expired nonces. It runs at most once per ttl.
+func (m *SyncMapNonceStore) prune(now int64) {
+	last := m.lastPrune.Load()
+	if now-last < int64(m.ttl) || !m.lastPrune.CompareAndSwap(last, now) {
+		return
+	}
+
+	m.expiries.Range(func(key, value any) bool {
+		if now >= value.(int64) {
+			m.expiries.CompareAndDelete(key, value)
+		}
+		return true
+	})
+}
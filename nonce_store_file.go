@@ -0,0 +1,229 @@
+//go:build !(js && wasm)
+
+package nep413
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// minCompactRecords is the number of records a file must have before it is compacted.
+const minCompactRecords = 1024
+
+// FileNonceStore is a NonceStore that persists nonces to an append-only file,
+// so that replay protection survives restarts of a single-node service.
+// Records older than the store's ttl are dropped when the file is loaded, and the
+// file is compacted once it holds more than twice as many records as are live.
+type FileNonceStore struct {
+	ttl  time.Duration
+	path string
+
+	mu       sync.Mutex
+	file     *os.File
+	expiries map[nonceKey]time.Time
+	// records is the number of records in the file
+	records int
+}
+
+var (
+	_ NonceStore    = (*FileNonceStore)(nil)
+	_ NonceReserver = (*FileNonceStore)(nil)
+)
+
+// fileNonceRecord is a single line of the nonce file.
+type fileNonceRecord struct {
+	Recipient string   `json:"recipient"`
+	Nonce     [32]byte `json:"nonce"`
+	// Timestamp is the unix time in milliseconds the nonce was used at
+	Timestamp int64 `json:"timestamp"`
+	// Expiry is the unix time in milliseconds the nonce expires at, if it was reserved for
+	// another ttl than the store's. Otherwise, it expires the store's ttl after Timestamp.
+	Expiry int64 `json:"expiry,omitempty"`
+}
+
+// NewFileNonceStore opens (or creates) the nonce file at path, loading the nonces
+// used within the last ttl.
+func NewFileNonceStore(path string, ttl time.Duration) (*FileNonceStore, error) {
+	s := &FileNonceStore{
+		ttl:      ttl,
+		path:     path,
+		expiries: make(map[nonceKey]time.Time),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	// rewrite the file without the expired records
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Seen implements NonceStore.
+func (s *FileNonceStore) Seen(ctx context.Context, recipient string, nonce [32]byte) (bool, error) {
+	reserved, err := s.Reserve(ctx, recipient, nonce, s.ttl)
+	return !reserved, err
+}
+
+// Reserve implements NonceReserver. The reservation is durable before it is reported.
+func (s *FileNonceStore) Reserve(_ context.Context, recipient string, nonce [32]byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	key := nonceKey{recipient, nonce}
+	if expiry, ok := s.expiries[key]; ok && now.Before(expiry) {
+		return false, nil
+	}
+
+	// compact before appending, so that a failed compaction fails the reservation before the
+	// nonce is recorded, and the caller can retry it
+	if s.records >= minCompactRecords && s.records >= 2*len(s.expiries) {
+		if err := s.compact(); err != nil {
+			return false, err
+		}
+	}
+
+	record := fileNonceRecord{
+		Recipient: recipient,
+		Nonce:     nonce,
+		Timestamp: now.UnixMilli(),
+	}
+	if ttl != s.ttl {
+		record.Expiry = now.Add(ttl).UnixMilli()
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return false, err
+	}
+
+	// the nonce must be durable before it is reported as unused
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return false, err
+	}
+	if err := s.file.Sync(); err != nil {
+		return false, err
+	}
+
+	s.expiries[key] = now.Add(ttl)
+	s.records++
+
+	return true, nil
+}
+
+// Has implements NonceStore.
+func (s *FileNonceStore) Has(_ context.Context, recipient string, nonce [32]byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiry, ok := s.expiries[nonceKey{recipient, nonce}]
+	return ok && time.Now().Before(expiry), nil
+}
+
+// Close closes the nonce file.
+func (s *FileNonceStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// load reads the live records from the nonce file, if it exists.
+func (s *FileNonceStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	now := time.Now()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record fileNonceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// a partial line is expected if the process died mid write
+			continue
+		}
+
+		expiry := time.UnixMilli(record.Timestamp).Add(s.ttl)
+		if record.Expiry != 0 {
+			expiry = time.UnixMilli(record.Expiry)
+		}
+		if now.Before(expiry) {
+			s.expiries[nonceKey{record.Recipient, record.Nonce}] = expiry
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact drops expired nonces, and atomically rewrites the file with the live ones.
+func (s *FileNonceStore) compact() error {
+	now := time.Now()
+	for key, expiry := range s.expiries {
+		if !now.Before(expiry) {
+			delete(s.expiries, key)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for key, expiry := range s.expiries {
+		line, err := json.Marshal(fileNonceRecord{
+			Recipient: key.recipient,
+			Nonce:     key.nonce,
+			Timestamp: expiry.Add(-s.ttl).UnixMilli(),
+			Expiry:    expiry.UnixMilli(),
+		})
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(append(line, '\n'))
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace nonce file: %w", err)
+	}
+
+	if s.file != nil {
+		s.file.Close()
+	}
+	s.file, err = os.OpenFile(s.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	s.records = len(s.expiries)
+
+	return nil
+}
@@ -0,0 +1,150 @@
+//go:build !(js && wasm)
+
+package nep413_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_FileNonceStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "nonces")
+
+	store, err := nep413.NewFileNonceStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only one concurrent call sees the nonce as unused
+	var unused atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			seen, err := store.Seen(ctx, "idos.network", [32]byte{1})
+			if err != nil {
+				t.Error(err)
+			}
+			if !seen {
+				unused.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if unused.Load() != 1 {
+		t.Fatalf("expected exactly one unused result, got %d", unused.Load())
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// nonces survive a restart
+	store, err = nep413.NewFileNonceStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	seen, err := store.Seen(ctx, "idos.network", [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !seen {
+		t.Fatal("expected nonce to be loaded from file")
+	}
+
+	seen, err = store.Seen(ctx, "other.app", [32]byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen {
+		t.Fatal("expected nonces to be scoped to the recipient")
+	}
+}
+
+func Test_FileNonceStoreReserve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nonces")
+	store, err := nep413.NewFileNonceStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testNonceStoreReserve(t, store)
+
+	// reservations keep their ttl across a restart
+	if ok, err := store.Reserve(context.Background(), "idos.network", [32]byte{3}, 50*time.Millisecond); err != nil || !ok {
+		t.Fatalf("expected a reservation, got %v %v", ok, err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = nep413.NewFileNonceStore(path, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if has, err := store.Has(context.Background(), "idos.network", [32]byte{2}); err != nil || !has {
+		t.Fatalf("expected the reservation to be loaded from file, got %v %v", has, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if has, err := store.Has(context.Background(), "idos.network", [32]byte{3}); err != nil || has {
+		t.Fatalf("expected the reservation to expire, got %v %v", has, err)
+	}
+}
+
+func Test_FileNonceStoreHas(t *testing.T) {
+	store, err := nep413.NewFileNonceStore(filepath.Join(t.TempDir(), "nonces"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	testNonceStoreHas(t, store)
+}
+
+func Test_FileNonceStoreFailedCompaction(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "nonces")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := nep413.NewFileNonceStore(filepath.Join(dir, "nonces"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// reservations that expire at once leave records to compact away
+	for i := 0; i < 1024; i++ {
+		if _, err := store.Reserve(ctx, "idos.network", [32]byte{1}, time.Nanosecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// compaction fails once the directory is gone, which fails the reservation
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reserve(ctx, "idos.network", [32]byte{2}, time.Hour); err == nil {
+		t.Fatal("expected the reservation to fail")
+	}
+
+	// but the nonce was not recorded, so it is not reported as used
+	if used, err := store.Has(ctx, "idos.network", [32]byte{2}); err != nil || used {
+		t.Fatalf("expected the nonce to be unused, got %v (%v)", used, err)
+	}
+}
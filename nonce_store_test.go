@@ -0,0 +1,157 @@
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_WithNonceStore(t *testing.T) {
+	msg, res := testVector()
+	store := nep413.NewMemoryNonceStore(time.Hour)
+
+	if err := nep413.Verify(msg, res, nep413.WithNonceStore(store)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.Verify(msg, res, nep413.WithNonceStore(store)); !errors.Is(err, nep413.ErrNonceReused) {
+		t.Fatalf("expected ErrNonceReused, got %v", err)
+	}
+
+	// invalid signatures do not consume nonces
+	msg, res = testVector()
+	msg.Recipient = "other.app"
+	if err := nep413.Verify(msg, res, nep413.WithNonceStore(store)); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+	if seen, _ := store.Seen(context.Background(), "other.app", msg.Nonce); seen {
+		t.Fatal("expected nonce to not be consumed")
+	}
+}
+
+func Test_SyncMapNonceStore(t *testing.T) {
+	ctx := context.Background()
+	store := nep413.NewSyncMapNonceStore(50 * time.Millisecond)
+
+	seen, err := store.Seen(ctx, "idos.network", [32]byte{1})
+	if err != nil || seen {
+		t.Fatalf("expected unused nonce, got %v %v", seen, err)
+	}
+
+	seen, err = store.Seen(ctx, "idos.network", [32]byte{1})
+	if err != nil || !seen {
+		t.Fatalf("expected used nonce, got %v %v", seen, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	seen, err = store.Seen(ctx, "idos.network", [32]byte{1})
+	if err != nil || seen {
+		t.Fatalf("expected nonce to expire, got %v %v", seen, err)
+	}
+}
+
+// BenchmarkNonceStores compares the nonce stores under a read-heavy, concurrent
+// workload, where 9 in 10 calls see an already used nonce.
+func BenchmarkNonceStores(b *testing.B) {
+	stores := map[string]nep413.NonceStore{
+		"mutex":    nep413.NewMemoryNonceStore(time.Hour),
+		"sync.Map": nep413.NewSyncMapNonceStore(time.Hour),
+	}
+
+	for name, store := range stores {
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+			used := make([][32]byte, 1024)
+			for i := range used {
+				used[i] = [32]byte{byte(i), byte(i >> 8)}
+				store.Seen(ctx, "idos.network", used[i])
+			}
+
+			var workers atomic.Uint32
+			b.RunParallel(func(pb *testing.PB) {
+				worker := byte(workers.Add(1))
+				for n := 0; pb.Next(); n++ {
+					nonce := used[n%len(used)]
+					if n%10 == 0 {
+						nonce = [32]byte{1, worker, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)}
+					}
+					store.Seen(ctx, "idos.network", nonce)
+				}
+			})
+		})
+	}
+}
+
+func Test_NonceStoreHas(t *testing.T) {
+	testNonceStoreHas(t, nep413.NewMemoryNonceStore(time.Hour))
+	testNonceStoreHas(t, nep413.NewSyncMapNonceStore(time.Hour))
+}
+
+// testNonceStoreHas checks that Has reports used nonces without marking them.
+func testNonceStoreHas(t *testing.T, store nep413.NonceStore) {
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		has, err := store.Has(ctx, "idos.network", [32]byte{1})
+		if err != nil || has {
+			t.Fatalf("expected unused nonce, got %v %v", has, err)
+		}
+	}
+
+	if _, err := store.Seen(ctx, "idos.network", [32]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	has, err := store.Has(ctx, "idos.network", [32]byte{1})
+	if err != nil || !has {
+		t.Fatalf("expected used nonce, got %v %v", has, err)
+	}
+}
+
+func Test_NonceStoreReserve(t *testing.T) {
+	testNonceStoreReserve(t, nep413.NewMemoryNonceStore(time.Hour))
+	testNonceStoreReserve(t, nep413.NewSyncMapNonceStore(time.Hour))
+}
+
+// testNonceStoreReserve checks that only one of concurrent reservations of a nonce succeeds,
+// and that reservations expire after their own ttl, rather than the store's.
+func testNonceStoreReserve(t *testing.T, store nep413.NonceReserver) {
+	ctx := context.Background()
+
+	var reserved atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := store.Reserve(ctx, "idos.network", [32]byte{2}, 50*time.Millisecond)
+			if err != nil {
+				t.Error(err)
+			}
+			if ok {
+				reserved.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved.Load() != 1 {
+		t.Fatalf("expected exactly one reservation, got %d", reserved.Load())
+	}
+
+	if seen, err := store.(nep413.NonceStore).Seen(ctx, "idos.network", [32]byte{2}); err != nil || !seen {
+		t.Fatalf("expected the reserved nonce to be used, got %v %v", seen, err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+
+	if ok, err := store.Reserve(ctx, "idos.network", [32]byte{2}, time.Hour); err != nil || !ok {
+		t.Fatalf("expected the reservation to expire, got %v %v", ok, err)
+	}
+}
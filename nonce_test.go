@@ -0,0 +1,217 @@
+package nep413_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+// timestampedNonce returns a timestamped nonce for the given time.
+func timestampedNonce(t *testing.T, ts time.Time) [32]byte {
+	generator := nep413.NonceGenerator{NowFunc: func() time.Time { return ts }}
+	nonce, err := generator.NewTimestampedNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return nonce
+}
+
+func Test_WithNonceFreshness(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		nonce [32]byte
+		err   error
+	}{
+		{"fresh", timestampedNonce(t, now.Add(-time.Minute)), nil},
+		{"within skew", timestampedNonce(t, now.Add(10*time.Second)), nil},
+		{"expired", timestampedNonce(t, now.Add(-10*time.Minute)), nep413.ErrNonceExpired},
+		{"future", timestampedNonce(t, now.Add(time.Minute)), nep413.ErrNonceInFuture},
+		{"random", [32]byte{1, 2, 3}, nep413.ErrNonceNotTimestamped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, _ := testVector()
+			msg.Nonce = tt.nonce
+			res, _ := testSign(t, msg)
+
+			err := nep413.Verify(msg, res, nep413.WithNonceFreshness(5*time.Minute, 30*time.Second))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func Test_NonceTimestamp(t *testing.T) {
+	nonce, err := nep413.NewTimestampedNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, ok := nep413.NonceTimestamp(nonce)
+	if !ok || time.Since(ts) > time.Minute {
+		t.Fatalf("unexpected timestamp %v", ts)
+	}
+
+	random, err := nep413.NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := nep413.NonceTimestamp(random); ok {
+		t.Fatal("expected random nonce to not be timestamped")
+	}
+}
+
+func Test_RejectFutureNonce(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name  string
+		nonce [32]byte
+		err   error
+	}{
+		{"old", timestampedNonce(t, now.Add(-24*time.Hour)), nil},
+		{"within skew", timestampedNonce(t, now.Add(10*time.Second)), nil},
+		{"future", timestampedNonce(t, now.Add(time.Minute)), nep413.ErrNonceInFuture},
+		{"random", [32]byte{1, 2, 3}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, _ := testVector()
+			msg.Nonce = tt.nonce
+			res, _ := testSign(t, msg)
+
+			err := nep413.Verify(msg, res, nep413.RejectFutureNonce(30*time.Second))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func Test_RejectFutureNonceWithFreshness(t *testing.T) {
+	now := time.Now()
+	msg, _ := testVector()
+	msg.Nonce = timestampedNonce(t, now.Add(time.Minute))
+	res, _ := testSign(t, msg)
+
+	loose := nep413.WithNonceFreshness(time.Hour, 5*time.Minute)
+	tight := nep413.RejectFutureNonce(30 * time.Second)
+
+	// the tighter skew applies, whichever order the options are given in
+	for name, opts := range map[string][]nep413.VerifyOption{
+		"freshness first": {loose, tight},
+		"freshness last":  {tight, loose},
+	} {
+		if err := nep413.Verify(msg, res, opts...); !errors.Is(err, nep413.ErrNonceInFuture) {
+			t.Fatalf("%s: expected ErrNonceInFuture, got %v", name, err)
+		}
+	}
+
+	// and a tighter freshness skew is not loosened by RejectFutureNonce
+	err := nep413.Verify(msg, res, nep413.WithNonceFreshness(time.Hour, 30*time.Second), nep413.RejectFutureNonce(5*time.Minute))
+	if !errors.Is(err, nep413.ErrNonceInFuture) {
+		t.Fatalf("expected ErrNonceInFuture, got %v", err)
+	}
+
+	if err := nep413.Verify(msg, res, loose, nep413.RejectFutureNonce(5*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_WithRequestIDNonce(t *testing.T) {
+	salt := []byte("0123456789abcdef0123456789abcdef")
+
+	if nep413.NonceFromRequestID("req-1", salt) != nep413.NonceFromRequestID("req-1", salt) {
+		t.Fatal("expected the nonce to be deterministic")
+	}
+	if nep413.NonceFromRequestID("req-1", salt) == nep413.NonceFromRequestID("req-2", salt) ||
+		nep413.NonceFromRequestID("req-1", salt) == nep413.NonceFromRequestID("req-1", []byte("other")) {
+		t.Fatal("expected distinct nonces")
+	}
+
+	msg, _ := testVector()
+	msg.Nonce = nep413.NonceFromRequestID("req-1", salt)
+	res, _ := testSign(t, msg)
+
+	if err := nep413.Verify(msg, res, nep413.WithRequestIDNonce("req-1", salt)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.Verify(msg, res, nep413.WithRequestIDNonce("req-2", salt)); !errors.Is(err, nep413.ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func Test_WithExpectedNonce(t *testing.T) {
+	msg, res := testVector()
+
+	opts := []nep413.VerifyOption{nep413.WithExpectedNonce(msg.Nonce), nep413.WithExpectedRecipient("idos.network")}
+	if err := nep413.Verify(msg, res, opts...); err != nil {
+		t.Fatal(err)
+	}
+
+	other := msg.Nonce
+	other[31] ^= 1
+	if err := nep413.Verify(msg, res, nep413.WithExpectedNonce(other)); !errors.Is(err, nep413.ErrNonceMismatch) {
+		t.Fatalf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func Test_NonceGenerator(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	newGenerator := func() *nep413.NonceGenerator {
+		return &nep413.NonceGenerator{
+			Rand:    bytes.NewReader(bytes.Repeat([]byte{7}, 64)),
+			NowFunc: func() time.Time { return now },
+		}
+	}
+
+	first, err := newGenerator().NewTimestampedNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newGenerator().NewTimestampedNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Fatal("expected the same nonce from the same sources")
+	}
+	if ts, ok := nep413.NonceTimestamp(first); !ok || !ts.Equal(now) {
+		t.Fatalf("expected timestamp %v, got %v", now, ts)
+	}
+
+	random, err := newGenerator().NewNonce()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if random != [32]byte(bytes.Repeat([]byte{7}, 32)) {
+		t.Fatalf("unexpected nonce %v", random)
+	}
+
+	// a short random source is an error
+	short := nep413.NonceGenerator{Rand: bytes.NewReader([]byte{1})}
+	if _, err := short.NewNonce(); err == nil {
+		t.Fatal("expected an error for a short random source")
+	}
+
+	// the whole flow is deterministic with a fixed clock
+	msg, _ := testVector()
+	msg.Nonce = first
+	res, _ := testSign(t, msg)
+	freshness := nep413.WithNonceFreshness(time.Minute, 0)
+	if err := nep413.Verify(msg, res, freshness, nep413.WithClock(func() time.Time { return now.Add(30 * time.Second) })); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.Verify(msg, res, freshness, nep413.WithClock(func() time.Time { return now.Add(2 * time.Minute) })); !errors.Is(err, nep413.ErrNonceExpired) {
+		t.Fatalf("expected ErrNonceExpired, got %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+package nep413
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	// ErrMissingCallbackURL is returned when RequireCallbackURL is set and the signed message has no callback url.
+	ErrMissingCallbackURL = errors.New("signed message has no callback url")
+	// ErrUnexpectedCallbackURL is returned when ForbidCallbackURL is set and the signed message has a callback url.
+	ErrUnexpectedCallbackURL = errors.New("signed message has a callback url")
+	// ErrMessageTooShort is returned when the signed message is shorter than WithMessageLengthRange allows.
+	ErrMessageTooShort = errors.New("signed message is too short")
+	// ErrMessageTooLong is returned when the signed message is longer than WithMessageLengthRange allows.
+	ErrMessageTooLong = errors.New("signed message is too long")
+	// ErrInvalidSignatureEncoding is returned when the response's signature is neither base64 nor
+	// NEAR's prefixed base58, or does not decode to a signature of the key's size. It is
+	// returned wrapped in ErrVerificationFailed.
+	ErrInvalidSignatureEncoding = errors.New("invalid signature encoding")
+)
+
+// VerifyOption configures an optional check applied during verification.
+// All checks operate on the signed message, never on client-supplied extras.
+type VerifyOption func(*verifyConfig)
+
+// verifyConfig holds the policy built from a set of VerifyOptions.
+type verifyConfig struct {
+	requireCallbackURL bool
+	forbidCallbackURL  bool
+	// minMessageLength and maxMessageLength bound the byte length of the message, or are 0 for no bound
+	minMessageLength int
+	maxMessageLength int
+	// allowEmptyMessage exempts empty messages from minMessageLength
+	allowEmptyMessage bool
+	// allowedAccounts matches the accounts permitted, or is nil for any account
+	allowedAccounts *AccountMatcher
+	// limiter rate limits verified signatures per signer, or nil for no limit
+	limiter *rateLimiter
+	// hashMode is how the payload is prepared for signing
+	hashMode HashMode
+	// expectedRecipients are the recipients the message may be signed for, or nil for any
+	expectedRecipients []string
+	// rejectEmptyRecipient rejects messages signed for an empty recipient
+	rejectEmptyRecipient bool
+	// nonceMaxAge and nonceFreshnessSkew bound the timestamp of the nonce as set by
+	// WithNonceFreshness, and nonceFutureSkew as set by RejectFutureNonce, or are nil for no bound
+	nonceMaxAge        *time.Duration
+	nonceFreshnessSkew *time.Duration
+	nonceFutureSkew    *time.Duration
+	// expectedNonce is the nonce the message must be signed with, or nil for any
+	expectedNonce *[32]byte
+	// nonceStore records consumed nonces, or is nil for no replay protection
+	nonceStore NonceStore
+	// signatureStore records accepted signatures, or is nil to allow reuse
+	signatureStore SignatureStore
+	// strictEncoding rejects non-canonical base64 signature encodings
+	strictEncoding bool
+	// rejectWeakKeys rejects signatures validated by small-order public keys
+	rejectWeakKeys bool
+	// allowedKeyTypes are the key types that may sign, or nil for any supported type
+	allowedKeyTypes []KeyType
+	// allowedCallbackHosts are the host patterns the callback url may point at, or nil for any
+	allowedCallbackHosts []string
+	// callbackMatchesRecipient requires the callback url's host to be within the recipient's domain
+	callbackMatchesRecipient bool
+	// messageNormalization is the Unicode normalization form applied to the message, or nil for none
+	messageNormalization *norm.Form
+	// presetTag uses the message's tag as is, rather than setting it
+	presetTag bool
+	// tag is the tag the payload is serialized with, or nil for Nep413Tag
+	tag *uint32
+	// accessKeys looks up the signing key on the account, or is nil to skip the check
+	accessKeys AccessKeyViewer
+	// requireFullAccess rejects function call access keys
+	requireFullAccess bool
+	// accessKeyCacheTTL is how long looked up access keys are cached for, or 0 for no cache
+	accessKeyCacheTTL time.Duration
+	// negativeCacheTTL is how long keys found not to exist are cached for, or 0 for no cache
+	negativeCacheTTL time.Duration
+	// accessKeyCacheSize is the number of keys the cache holds, or 0 for the default
+	accessKeyCacheSize int
+	// accessKeyCache caches accessKeys, if accessKeyCacheTTL or negativeCacheTTL is set
+	accessKeyCache *accessKeyCache
+	// nowFunc is the clock for time-based checks, or nil for time.Now
+	nowFunc func() time.Time
+	// tryTrimmedRecipient also tries the signature with a whitespace trimmed recipient
+	tryTrimmedRecipient bool
+	// deriveImplicitAccount uses the signing key's implicit account if the response has no account id
+	deriveImplicitAccount bool
+}
+
+func newVerifyConfig(opts []VerifyOption) *verifyConfig {
+	cfg := &verifyConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	if cfg.accessKeys != nil && (cfg.accessKeyCacheTTL > 0 || cfg.negativeCacheTTL > 0) {
+		cfg.accessKeyCache = newAccessKeyCache(cfg.accessKeys, cfg.accessKeyCacheTTL, cfg.negativeCacheTTL, cfg.accessKeyCacheSize)
+		cfg.accessKeys = cfg.accessKeyCache
+	}
+
+	return cfg
+}
+
+// WithClock sets the clock used instead of time.Now by the time-based checks, i.e. nonce
+// freshness, the rate limit and the expiry of cached access keys, e.g. a fixed clock for
+// deterministic tests. It is the option
+// form of Verifier.NowFunc, which takes precedence over it.
+func WithClock(now func() time.Time) VerifyOption {
+	return func(c *verifyConfig) {
+		c.nowFunc = now
+	}
+}
+
+// now returns the current time, according to the configured clock.
+// All time-based checks go through it.
+func (c *verifyConfig) now() time.Time {
+	if c.nowFunc != nil {
+		return c.nowFunc()
+	}
+
+	return time.Now()
+}
+
+// RequireCallbackURL fails verification with ErrMissingCallbackURL if the
+// signed message does not contain a non-empty callback url.
+func RequireCallbackURL() VerifyOption {
+	return func(c *verifyConfig) {
+		c.requireCallbackURL = true
+	}
+}
+
+// ForbidCallbackURL fails verification with ErrUnexpectedCallbackURL if the
+// signed message contains a callback url. An empty callback url still counts,
+// since it is serialized into the payload.
+func ForbidCallbackURL() VerifyOption {
+	return func(c *verifyConfig) {
+		c.forbidCallbackURL = true
+	}
+}
+
+// WithMessageLengthRange fails verification with ErrMessageTooShort or ErrMessageTooLong
+// unless the signed message is between min and max bytes long, inclusive, as UTF-8, e.g.
+// to require a meaningful challenge string. The length is that of the message text as
+// signed, i.e. after WithMessageNormalization. A max of 0 means no maximum, and a min of 1
+// rejects empty messages. By default, messages of any length pass.
+func WithMessageLengthRange(min, max int) VerifyOption {
+	return func(c *verifyConfig) {
+		c.minMessageLength = min
+		c.maxMessageLength = max
+	}
+}
+
+// AllowEmptyMessage lets an empty signed message pass the minimum of WithMessageLengthRange,
+// while non-empty messages are still held to it, e.g. for proof-of-possession flows that sign
+// an empty message only to prove control of a key. An empty message carries no domain context,
+// so the recipient and nonce are all that bind the signature to its purpose: combine it with
+// WithExpectedRecipients and a nonce check (WithExpectedNonce or WithNonceFreshness).
+// Without a minimum length, empty messages are accepted anyway.
+func AllowEmptyMessage() VerifyOption {
+	return func(c *verifyConfig) {
+		c.allowEmptyMessage = true
+	}
+}
+
+// checkMessageLength checks the byte length of the signed message text.
+func (c *verifyConfig) checkMessageLength(msg *Nep413Message) error {
+	text := msg.Message
+	if c.messageNormalization != nil {
+		text = c.messageNormalization.String(text)
+	}
+
+	if len(text) < c.minMessageLength && (text != "" || !c.allowEmptyMessage) {
+		return ErrMessageTooShort
+	}
+	if c.maxMessageLength > 0 && len(text) > c.maxMessageLength {
+		return ErrMessageTooLong
+	}
+
+	return nil
+}
+
+// allow applies the rate limit, if any, to the signer of a verified signature: the response's
+// account if bound is set, i.e. the account is tied to the signing key, or the key otherwise.
+func (c *verifyConfig) allow(res *Nep413SignatureResponse, match *signatureMatch, bound bool) error {
+	if c.limiter == nil {
+		return nil
+	}
+
+	// account ids cannot contain a ":", so they never collide with a formatted key
+	key := match.nearKey()
+	if bound {
+		key = normalizeAccountID(res.AccountId)
+	}
+
+	if !c.limiter.allow(key, c.now()) {
+		return ErrRateLimited
+	}
+
+	return nil
+}
+
+// VerifyStrict enables all hardening checks that a conforming wallet always passes:
+//   - RejectEmptyRecipient
+//   - signatures must be canonically base64 encoded, so an encoding cannot be altered
+//     without invalidating it
+//   - small-order public keys, which validate forged signatures, are rejected with ErrWeakPublicKey
+//
+// Verification is lenient by default, to preserve existing behavior.
+func VerifyStrict() VerifyOption {
+	return func(c *verifyConfig) {
+		RejectEmptyRecipient()(c)
+		c.strictEncoding = true
+		c.rejectWeakKeys = true
+	}
+}
+
+// signatureEncoding returns the encoding the response's signature is decoded with.
+func (c *verifyConfig) signatureEncoding() *base64.Encoding {
+	if c.strictEncoding {
+		return base64.StdEncoding.Strict()
+	}
+
+	return base64.StdEncoding
+}
+
+// decodeSignature decodes the response's signature for a key of the given type, failing with
+// ErrInvalidSignatureEncoding unless it decodes to a signature of the right size. Signatures
+// are accepted in these formats:
+//   - standard base64, as wallets return them (canonically encoded with VerifyStrict)
+//   - the key type's prefix followed by base58 with Base58Alphabet, as NEAR formats
+//     signatures, e.g. "ed25519:25qQjLe4pkeetp4iwNVKydsgieQhUGuNsKhqYrseSv3SSkzd61PNdeGYnWwYAS5d3qhNZDx8wKCFmUxYbubaJNAH"
+//
+// A prefix other than the key type's is rejected.
+func (c *verifyConfig) decodeSignature(s string, keyType KeyType) ([]byte, error) {
+	var signature []byte
+	if algorithm, encoded, ok := strings.Cut(s, ":"); ok {
+		if algorithm != keyType.String() {
+			return nil, fmt.Errorf("%w: %w: %q prefix on a signature for a %s key", ErrVerificationFailed, ErrInvalidSignatureEncoding, algorithm, keyType)
+		}
+
+		decoded, err := decodeBase58(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %w", ErrVerificationFailed, ErrInvalidSignatureEncoding, err)
+		}
+		signature = decoded
+	} else {
+		decoded, err := c.signatureEncoding().DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %w", ErrVerificationFailed, ErrInvalidSignatureEncoding, err)
+		}
+		signature = decoded
+	}
+
+	// secp256k1 signatures may carry a trailing recovery id
+	if len(signature) != ed25519.SignatureSize && (keyType != KeyTypeSECP256K1 || len(signature) != ed25519.SignatureSize+1) {
+		return nil, fmt.Errorf("%w: %w: decoded to %d bytes", ErrVerificationFailed, ErrInvalidSignatureEncoding, len(signature))
+	}
+
+	return signature, nil
+}
+
+// checkMessage applies the message policy to the signed message.
+func (c *verifyConfig) checkMessage(msg *Nep413Message) error {
+	if err := c.checkMessageLength(msg); err != nil {
+		return err
+	}
+
+	if err := c.checkNonce(msg); err != nil {
+		return err
+	}
+
+	return c.checkCallback(msg)
+}
+
+// checkCallback applies the callback url policy to the signed message.
+func (c *verifyConfig) checkCallback(msg *Nep413Message) error {
+	if c.requireCallbackURL && (msg.CallbackUrl == nil || *msg.CallbackUrl == "") {
+		return ErrMissingCallbackURL
+	}
+
+	if c.forbidCallbackURL && msg.CallbackUrl != nil {
+		return ErrUnexpectedCallbackURL
+	}
+
+	if err := c.checkCallbackHost(msg); err != nil {
+		return err
+	}
+
+	return c.checkCallbackRecipient(msg)
+}
+
+// checkResponse applies the response policy after the signature has been verified.
+func (c *verifyConfig) checkResponse(res *Nep413SignatureResponse) error {
+	if c.allowedAccounts != nil && !c.allowedAccounts.Match(res.AccountId) {
+		return ErrAccountNotAllowed
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package nep413_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_CallbackURLOptions(t *testing.T) {
+	msg, res := testVector()
+
+	err := nep413.Verify(msg, res, nep413.RequireCallbackURL())
+	if !errors.Is(err, nep413.ErrMissingCallbackURL) {
+		t.Fatalf("expected ErrMissingCallbackURL, got %v", err)
+	}
+
+	err = nep413.Verify(msg, res, nep413.ForbidCallbackURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	callback := "https://idos.network/callback"
+	msg.CallbackUrl = &callback
+
+	err = nep413.Verify(msg, res, nep413.ForbidCallbackURL())
+	if !errors.Is(err, nep413.ErrUnexpectedCallbackURL) {
+		t.Fatalf("expected ErrUnexpectedCallbackURL, got %v", err)
+	}
+}
+
+func Test_WithMessageLengthRange(t *testing.T) {
+	msg, res := testVector()
+
+	if err := nep413.Verify(msg, res, nep413.WithMessageLengthRange(1, len(msg.Message))); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.Verify(msg, res, nep413.WithMessageLengthRange(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.Verify(msg, res, nep413.WithMessageLengthRange(len(msg.Message)+1, 0)); !errors.Is(err, nep413.ErrMessageTooShort) {
+		t.Fatalf("expected ErrMessageTooShort, got %v", err)
+	}
+	if err := nep413.Verify(msg, res, nep413.WithMessageLengthRange(0, len(msg.Message)-1)); !errors.Is(err, nep413.ErrMessageTooLong) {
+		t.Fatalf("expected ErrMessageTooLong, got %v", err)
+	}
+
+	// the length is in bytes, not characters
+	msg.Message = "\u00e9"
+	signed, _ := testSign(t, msg)
+	if err := nep413.Verify(msg, signed, nep413.WithMessageLengthRange(0, 1)); !errors.Is(err, nep413.ErrMessageTooLong) {
+		t.Fatalf("expected ErrMessageTooLong, got %v", err)
+	}
+
+	msg.Message = ""
+	empty, _ := testSign(t, msg)
+	if err := nep413.Verify(msg, empty, nep413.WithMessageLengthRange(1, 0)); !errors.Is(err, nep413.ErrMessageTooShort) {
+		t.Fatalf("expected ErrMessageTooShort, got %v", err)
+	}
+}
+
+func Test_AllowEmptyMessage(t *testing.T) {
+	msg, _ := testVector()
+	msg.Message = ""
+	empty, _ := testSign(t, msg)
+
+	if err := nep413.Verify(msg, empty); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.Verify(msg, empty, nep413.WithMessageLengthRange(8, 0), nep413.AllowEmptyMessage()); err != nil {
+		t.Fatal(err)
+	}
+
+	// other checks still apply
+	if err := nep413.Verify(msg, empty, nep413.AllowEmptyMessage(), nep413.WithExpectedRecipient("other.network")); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+
+	// non-empty messages are still held to the minimum
+	msg.Message = "short"
+	short, _ := testSign(t, msg)
+	if err := nep413.Verify(msg, short, nep413.WithMessageLengthRange(8, 0), nep413.AllowEmptyMessage()); !errors.Is(err, nep413.ErrMessageTooShort) {
+		t.Fatalf("expected ErrMessageTooShort, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package nep413
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SignMessageParams are the parameters of a wallet's signMessage call, as sent
+// to a frontend to request a signature.
+type SignMessageParams struct {
+	// Message is the plaintext message to sign
+	Message string
+	// Recipient is the string identifier of the recipient (e.g. satoshi.near)
+	Recipient string
+	// Nonce is the 32 byte nonce of the message
+	Nonce [32]byte
+	// CallbackUrl is the url the wallet redirects to, if any
+	CallbackUrl *string
+	// State is an opaque value the wallet echoes back in its response, if any.
+	// It is not part of the signed payload, so it is unauthenticated.
+	State string
+}
+
+// signMessageParamsJSON is the JSON form of SignMessageParams.
+type signMessageParamsJSON struct {
+	Message   string `json:"message"`
+	Recipient string `json:"recipient"`
+	// Nonce is base64 encoded, so a frontend can pass Buffer.from(nonce, "base64") to the wallet
+	Nonce       string  `json:"nonce"`
+	CallbackUrl *string `json:"callbackUrl,omitempty"`
+	State       string  `json:"state,omitempty"`
+}
+
+// MarshalJSON encodes the params as {message, recipient, nonce, callbackUrl, state}, with the
+// nonce base64 encoded. It matches the schema returned by SignMessageParamsSchema.
+func (p SignMessageParams) MarshalJSON() ([]byte, error) {
+	return json.Marshal(signMessageParamsJSON{
+		Message:     p.Message,
+		Recipient:   p.Recipient,
+		Nonce:       base64.StdEncoding.EncodeToString(p.Nonce[:]),
+		CallbackUrl: p.CallbackUrl,
+		State:       p.State,
+	})
+}
+
+// UnmarshalJSON decodes params encoded by MarshalJSON, failing unless the nonce is 32 base64
+// encoded bytes. An empty callbackUrl is kept, rather than treated as none, since it is signed.
+func (p *SignMessageParams) UnmarshalJSON(data []byte) error {
+	var decoded signMessageParamsJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(decoded.Nonce)
+	if err != nil {
+		return fmt.Errorf("invalid nonce encoding: %w", err)
+	}
+	if len(nonce) != len(p.Nonce) {
+		return fmt.Errorf("invalid nonce length, expected %d, got %d", len(p.Nonce), len(nonce))
+	}
+
+	p.Message = decoded.Message
+	p.Recipient = decoded.Recipient
+	copy(p.Nonce[:], nonce)
+	p.CallbackUrl = decoded.CallbackUrl
+	p.State = decoded.State
+
+	return nil
+}
+
+// nearApiJsSignRequest is the JSON form of the params expected by JavaScript wallets.
+type nearApiJsSignRequest struct {
+	Message   string `json:"message"`
+	Recipient string `json:"recipient"`
+	// Nonce is a []int, since encoding/json base64 encodes a []byte
+	Nonce       []int   `json:"nonce"`
+	CallbackUrl *string `json:"callbackUrl,omitempty"`
+	State       string  `json:"state,omitempty"`
+}
+
+// NearApiJsSignRequest encodes the params as the object passed to signMessage by frontends
+// built on near-api-js, following SignMessageParams of @near-wallet-selector/core v8:
+//
+//	{"message":...,"recipient":...,"nonce":[5,233,...],"callbackUrl":...,"state":...}
+//
+// The library types the nonce as a 32 byte Buffer, which has no JSON form of its own, so it
+// is encoded as an array of its bytes, for the frontend to pass on as Buffer.from(nonce).
+// Unlike MarshalJSON, which base64 encodes the nonce, the object can be passed through as is.
+func NearApiJsSignRequest(params SignMessageParams) ([]byte, error) {
+	nonce := make([]int, len(params.Nonce))
+	for i, b := range params.Nonce {
+		nonce[i] = int(b)
+	}
+
+	return json.Marshal(nearApiJsSignRequest{
+		Message:     params.Message,
+		Recipient:   params.Recipient,
+		Nonce:       nonce,
+		CallbackUrl: params.CallbackUrl,
+		State:       params.State,
+	})
+}
+
+// Nep413Message returns the message the wallet signs for these params.
+// State is not part of it.
+func (p SignMessageParams) Nep413Message() *Nep413Message {
+	return &Nep413Message{
+		Message:     p.Message,
+		Nonce:       p.Nonce,
+		Recipient:   p.Recipient,
+		CallbackUrl: p.CallbackUrl,
+	}
+}
+
+// ErrRoundTripMismatch is returned by ValidateParamsRoundTrip when the params sent to a
+// frontend would not yield the message they were built for.
+var ErrRoundTripMismatch = errors.New("sign message params do not round trip")
+
+// ValidateParamsRoundTrip checks that the params survive the trip to a frontend and back:
+// it encodes them as JSON, decodes them again, and compares the hash of the payload the
+// wallet would sign for the decoded params with that of the message built directly from
+// them. It fails with ErrRoundTripMismatch if they differ, guarding against the request
+// building and verification code paths diverging, e.g. in tests or at startup.
+func ValidateParamsRoundTrip(params SignMessageParams) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	var decoded SignMessageParams
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("%w: %w", ErrRoundTripMismatch, err)
+	}
+
+	want, err := serializePayload(params.Nep413Message())
+	if err != nil {
+		return err
+	}
+	got, err := serializePayload(decoded.Nep413Message())
+	if err != nil {
+		return err
+	}
+
+	if sha256.Sum256(got) != sha256.Sum256(want) {
+		return fmt.Errorf("%w: %s", ErrRoundTripMismatch, data)
+	}
+
+	return nil
+}
+
+// signMessageParamsSchema is the JSON Schema of the JSON form of SignMessageParams.
+const signMessageParamsSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "SignMessageParams",
+  "description": "Parameters of an NEP-413 signMessage request",
+  "type": "object",
+  "properties": {
+    "message": {
+      "type": "string",
+      "description": "The plaintext message to sign"
+    },
+    "recipient": {
+      "type": "string",
+      "description": "The recipient the message is signed for, e.g. satoshi.near"
+    },
+    "nonce": {
+      "type": "string",
+      "description": "The 32 byte nonce, base64 encoded",
+      "contentEncoding": "base64",
+      "pattern": "^[A-Za-z0-9+/]{43}=$"
+    },
+    "callbackUrl": {
+      "type": "string",
+      "description": "The url the wallet redirects to. An empty url differs from none, since it is signed",
+      "anyOf": [{"format": "uri"}, {"const": ""}]
+    },
+    "state": {
+      "type": "string",
+      "description": "An opaque value echoed back by the wallet; it is not signed"
+    }
+  },
+  "required": ["message", "recipient", "nonce"],
+  "additionalProperties": false
+}
+`
+
+// SignMessageParamsSchema returns the JSON Schema describing the JSON form of SignMessageParams.
+func SignMessageParamsSchema() []byte {
+	return []byte(signMessageParamsSchema)
+}
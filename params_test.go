@@ -0,0 +1,157 @@
+package nep413_test
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_SignMessageParamsJSON(t *testing.T) {
+	msg, res := testVector()
+	callback := "https://idos.network/callback"
+	params := nep413.SignMessageParams{
+		Message:     msg.Message,
+		Recipient:   msg.Recipient,
+		Nonce:       msg.Nonce,
+		CallbackUrl: &callback,
+		State:       "session-1",
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded nep413.SignMessageParams
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.State != "session-1" {
+		t.Fatalf("expected state session-1, got %s", decoded.State)
+	}
+
+	decoded.CallbackUrl = nil
+	if err := nep413.Verify(decoded.Nep413Message(), res); err != nil {
+		t.Fatal(err)
+	}
+
+	// the marshaled params match the schema
+	var schema struct {
+		Properties map[string]struct {
+			Type    string `json:"type"`
+			Pattern string `json:"pattern"`
+			AnyOf   []struct {
+				Const *string `json:"const"`
+			} `json:"anyOf"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal(nep413.SignMessageParamsSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]string
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range fields {
+		property, ok := schema.Properties[name]
+		if !ok {
+			t.Fatalf("field %s is not in the schema", name)
+		}
+		if property.Pattern != "" && !regexp.MustCompile(property.Pattern).MatchString(value) {
+			t.Fatalf("field %s does not match pattern %s", name, property.Pattern)
+		}
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := fields[name]; !ok {
+			t.Fatalf("required field %s is missing", name)
+		}
+	}
+
+	// an empty callback url is signed, so it is encoded, kept, and allowed by the schema
+	empty := ""
+	params.CallbackUrl = &empty
+	if data, err = json.Marshal(params); err != nil {
+		t.Fatal(err)
+	}
+	decoded = nep413.SignMessageParams{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.CallbackUrl == nil || *decoded.CallbackUrl != "" {
+		t.Fatalf("expected an empty callback url, got %v", decoded.CallbackUrl)
+	}
+
+	allowsEmpty := false
+	for _, alternative := range schema.Properties["callbackUrl"].AnyOf {
+		if alternative.Const != nil && *alternative.Const == "" {
+			allowsEmpty = true
+		}
+	}
+	if !allowsEmpty {
+		t.Fatal("expected the schema to allow an empty callback url")
+	}
+}
+
+func Test_NearApiJsSignRequest(t *testing.T) {
+	msg, _ := testVector()
+	data, err := nep413.NearApiJsSignRequest(nep413.SignMessageParams{
+		Message:   msg.Message,
+		Recipient: msg.Recipient,
+		Nonce:     msg.Nonce,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request struct {
+		Message   string `json:"message"`
+		Recipient string `json:"recipient"`
+		Nonce     []int  `json:"nonce"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+
+	if request.Message != msg.Message || request.Recipient != msg.Recipient || len(request.Nonce) != len(msg.Nonce) {
+		t.Fatalf("unexpected request %s", data)
+	}
+	for i, b := range msg.Nonce {
+		if request.Nonce[i] != int(b) {
+			t.Fatalf("unexpected nonce byte %d: %d", i, request.Nonce[i])
+		}
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["callbackUrl"]; ok {
+		t.Fatal("expected no callbackUrl")
+	}
+}
+
+func Test_ValidateParamsRoundTrip(t *testing.T) {
+	msg, _ := testVector()
+	callback := "https://idos.network/callback"
+	empty := ""
+
+	for _, callbackUrl := range []*string{nil, &callback, &empty} {
+		params := nep413.SignMessageParams{
+			Message:     msg.Message,
+			Recipient:   msg.Recipient,
+			Nonce:       msg.Nonce,
+			CallbackUrl: callbackUrl,
+			State:       "session-1",
+		}
+		if err := nep413.ValidateParamsRoundTrip(params); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
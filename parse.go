@@ -0,0 +1,291 @@
+package nep413
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+// ErrMissingField is returned when a wallet response is missing a required field.
+var ErrMissingField = errors.New("wallet response is missing a required field")
+
+// ParseCallbackFragment parses a signature response returned by a redirect wallet
+// in the URL fragment, e.g. "#accountId=...&signature=...&publicKey=...".
+// The leading "#" is optional, and a full URL is also accepted. A fragment that was
+// percent-encoded as a whole is decoded first.
+// Base64 characters mangled in transport ("+" decoded as a space, or url-safe
+// "-" and "_") are restored in the signature. Signatures with a key type prefix, e.g.
+// "ed25519:...", are base58 encoded, so they are left as they are.
+func ParseCallbackFragment(fragment string) (*Nep413SignatureResponse, error) {
+	if i := strings.IndexByte(fragment, '#'); i >= 0 {
+		fragment = fragment[i+1:]
+	}
+
+	// the whole fragment was encoded, so the separators are escaped
+	if !strings.Contains(fragment, "=") {
+		unescaped, err := url.PathUnescape(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fragment encoding: %w", err)
+		}
+		fragment = unescaped
+	}
+
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fragment encoding: %w", err)
+	}
+
+	res := &Nep413SignatureResponse{
+		Signature: repairBase64(values.Get("signature")),
+		PublicKey: values.Get("publicKey"),
+		AccountId: values.Get("accountId"),
+		State:     values.Get("state"),
+	}
+
+	if res.Signature == "" {
+		return nil, fmt.Errorf("%w: signature", ErrMissingField)
+	}
+	if res.PublicKey == "" {
+		return nil, fmt.Errorf("%w: publicKey", ErrMissingField)
+	}
+
+	return res, nil
+}
+
+// repairBase64 restores a standard, padded base64 string that was mangled in transport.
+// Prefixed base58 strings, which cannot contain a ":" in base64, are only trimmed.
+func repairBase64(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, ":") {
+		return s
+	}
+	s = strings.NewReplacer(" ", "+", "-", "+", "_", "/").Replace(s)
+	if rem := len(s) % 4; rem != 0 {
+		s += strings.Repeat("=", 4-rem)
+	}
+
+	return s
+}
+
+// signedMessageEnvelope is the result returned by newer wallet-selector versions.
+type signedMessageEnvelope struct {
+	SignedMessage *Nep413SignatureResponse `json:"signedMessage"`
+	Nonce         json.RawMessage          `json:"nonce"`
+	Recipient     string                   `json:"recipient"`
+	Message       string                   `json:"message"`
+	CallbackUrl   *string                  `json:"callbackUrl"`
+	State         string                   `json:"state"`
+}
+
+// ParseSignedMessageEnvelope parses a wallet-selector result of the form
+// {signedMessage: {accountId, publicKey, signature}, nonce, recipient, message},
+// returning both the reconstructed message and the signature response.
+// The nonce may be in any of the encodings accepted by DecodeNonce. The callback url,
+// account id and state are optional; state is read from signedMessage, or from the
+// top level of the envelope.
+func ParseSignedMessageEnvelope(data []byte) (*Nep413Message, *Nep413SignatureResponse, error) {
+	var envelope signedMessageEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("invalid signed message envelope: %w", err)
+	}
+
+	if envelope.SignedMessage == nil {
+		return nil, nil, fmt.Errorf("%w: signedMessage", ErrMissingField)
+	}
+	if envelope.SignedMessage.Signature == "" {
+		return nil, nil, fmt.Errorf("%w: signature", ErrMissingField)
+	}
+	if envelope.SignedMessage.PublicKey == "" {
+		return nil, nil, fmt.Errorf("%w: publicKey", ErrMissingField)
+	}
+	if len(envelope.Nonce) == 0 || string(envelope.Nonce) == "null" {
+		return nil, nil, fmt.Errorf("%w: nonce", ErrMissingField)
+	}
+
+	if envelope.SignedMessage.State == "" {
+		envelope.SignedMessage.State = envelope.State
+	}
+
+	nonce, err := decodeNonceJSON(envelope.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg := &Nep413Message{
+		Message:     envelope.Message,
+		Nonce:       nonce,
+		Recipient:   envelope.Recipient,
+		CallbackUrl: envelope.CallbackUrl,
+	}
+
+	return msg, envelope.SignedMessage, nil
+}
+
+// ErrInvalidNonceEncoding is returned when a nonce is not in any of the encodings wallets use.
+var ErrInvalidNonceEncoding = errors.New("invalid nonce encoding")
+
+// DecodeNonce decodes a 32 byte nonce in any of the encodings wallets use:
+//   - a JSON byte array, e.g. "[5,233,107,...]"
+//   - padded standard base64 (recognized by its "=", "+" or "/" characters)
+//   - base58
+//   - unpadded standard or url-safe base64
+//
+// It returns ErrInvalidNonceEncoding if none of them decode to 32 bytes.
+func DecodeNonce(s string) ([32]byte, error) {
+	var nonce [32]byte
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "[") {
+		var decoded []byte
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil || len(decoded) != len(nonce) {
+			return nonce, ErrInvalidNonceEncoding
+		}
+		copy(nonce[:], decoded)
+		return nonce, nil
+	}
+
+	candidates := []func(string) ([]byte, error){
+		base58.Decode,
+		base64.RawStdEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+	}
+	// base58 never contains these characters, so the nonce must be base64
+	if strings.ContainsAny(s, "=+/") {
+		candidates = []func(string) ([]byte, error){base64.StdEncoding.DecodeString}
+	}
+
+	for _, decode := range candidates {
+		if decoded, err := decode(s); err == nil && len(decoded) == len(nonce) {
+			copy(nonce[:], decoded)
+			return nonce, nil
+		}
+	}
+
+	return nonce, ErrInvalidNonceEncoding
+}
+
+// decodeNonceJSON decodes a nonce from a JSON value, which is either a string
+// or a byte array, in any of the encodings accepted by DecodeNonce.
+func decodeNonceJSON(raw json.RawMessage) ([32]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return DecodeNonce(s)
+	}
+
+	return DecodeNonce(string(raw))
+}
+
+// ReconstructMessage assembles the message a wallet signed from the parameters the server
+// issued, for verifying a response that does not echo them back. It is the recommended step
+// before verifying such a response, so that every caller builds the message identically:
+// the tag is set, and an empty callback means none was requested (only a non-empty callback
+// becomes part of the payload).
+// The response is not part of the signed message; it is passed so that call sites read as
+// the pairing that is verified, e.g. Verify(ReconstructMessage(res, ...), res).
+func ReconstructMessage(res *Nep413SignatureResponse, recipient, message string, nonce [32]byte, callback string) *Nep413Message {
+	msg := &Nep413Message{
+		Tag:       Nep413Tag,
+		Message:   message,
+		Nonce:     nonce,
+		Recipient: recipient,
+	}
+
+	if callback != "" {
+		msg.CallbackUrl = &callback
+	}
+
+	return msg
+}
+
+// ParseResponse decodes the borsch serialization of a response, as produced by its
+// MarshalBinary method, including the signature and public key only form written by earlier
+// versions. It has no side effects, and never panics: truncated, oversized or otherwise
+// malformed input fails with ErrMalformedInput.
+func ParseResponse(data []byte) (*Nep413SignatureResponse, error) {
+	d, err := newBorschDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var res Nep413SignatureResponse
+	for _, field := range []*string{&res.Signature, &res.PublicKey} {
+		if *field, err = d.string(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the fields added since are optional, but MarshalBinary never ends on an empty one
+	for _, field := range res.binaryExtraFields() {
+		if len(d.data) == 0 {
+			break
+		}
+		if *field, err = d.string(); err != nil {
+			return nil, err
+		}
+		if len(d.data) == 0 && *field == "" {
+			return nil, fmt.Errorf("%w: empty trailing field", ErrMalformedInput)
+		}
+	}
+
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// ParseMessage decodes a borsch serialized message, i.e. a signed NEP-413 payload. The tag
+// is returned as is, and is not checked. Like ParseResponse, it has no side effects, and
+// never panics: malformed input fails with ErrMalformedInput.
+func ParseMessage(data []byte) (*Nep413Message, error) {
+	d, err := newBorschDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Nep413Message
+	if err := d.message(&msg); err != nil {
+		return nil, err
+	}
+
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+
+	return &msg, nil
+}
+
+// MessageInspection describes a borsch serialized message, as decoded by InspectMessageBytes.
+type MessageInspection struct {
+	// Message holds the decoded fields. For malformed input, it holds the fields decoded
+	// before the error.
+	Message *Nep413Message
+	// StandardTag is set if the tag is Nep413Tag
+	StandardTag bool
+	// TrailingBytes is the number of bytes left over after the message
+	TrailingBytes int
+}
+
+// InspectMessageBytes decodes every field of a stored borsch serialized message, e.g. for
+// migrating or inspecting stored payloads, reporting whether the tag is the standard
+// Nep413Tag. Unlike ParseMessage, it accepts trailing bytes, and only reports them.
+// It uses the bounds checked decoder, rather than borsh-go, so it never panics: malformed
+// input fails with ErrMalformedInput, along with the fields that could be decoded.
+func InspectMessageBytes(data []byte) (*MessageInspection, error) {
+	d, err := newBorschDecoder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	inspection := &MessageInspection{Message: &Nep413Message{}}
+	err = d.message(inspection.Message)
+	inspection.StandardTag = inspection.Message.Tag == Nep413Tag
+	inspection.TrailingBytes = len(d.data)
+
+	return inspection, err
+}
@@ -0,0 +1,193 @@
+package nep413_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+)
+
+func Test_ParseCallbackFragment(t *testing.T) {
+	msg, expected := testVector()
+
+	encoded := url.Values{
+		"accountId": {"idos.near"},
+		"signature": {expected.Signature},
+		"publicKey": {expected.PublicKey},
+	}.Encode()
+
+	fragments := map[string]string{
+		"encoded values":   "#" + encoded,
+		"encoded fragment": url.QueryEscape(encoded),
+		"raw":              "accountId=idos.near&signature=" + expected.Signature + "&publicKey=" + expected.PublicKey,
+		"full url":         "https://idos.network/callback#" + encoded,
+	}
+
+	for name, fragment := range fragments {
+		t.Run(name, func(t *testing.T) {
+			res, err := nep413.ParseCallbackFragment(fragment)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.AccountId != "idos.near" {
+				t.Fatalf("expected account id idos.near, got %s", res.AccountId)
+			}
+
+			if err := nep413.Verify(msg, res); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	// NEAR's prefixed base58 signatures are not repaired as base64
+	secp256k1Msg, secp256k1Res := testSecp256k1Vector()
+	signature, err := base64.StdEncoding.DecodeString(secp256k1Res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixed := "secp256k1:" + base58.Encode(signature)
+	if len(prefixed)%4 == 0 {
+		t.Fatal("expected a signature that base64 repair would pad")
+	}
+	res, err := nep413.ParseCallbackFragment("#" + url.Values{
+		"signature": {prefixed},
+		"publicKey": {secp256k1Res.PublicKey},
+	}.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Signature != prefixed {
+		t.Fatalf("expected signature %s, got %s", prefixed, res.Signature)
+	}
+	if err := nep413.Verify(secp256k1Msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = nep413.ParseCallbackFragment("#accountId=idos.near")
+	if err == nil {
+		t.Fatal("expected error for missing signature")
+	}
+}
+
+func Test_ParseSignedMessageEnvelope(t *testing.T) {
+	expectedMsg, expectedRes := testVector()
+
+	data, err := json.Marshal(map[string]any{
+		"signedMessage": map[string]string{
+			"accountId": "idos.near",
+			"publicKey": expectedRes.PublicKey,
+			"signature": expectedRes.Signature,
+		},
+		"nonce":     base58.Encode(expectedMsg.Nonce[:]),
+		"recipient": expectedMsg.Recipient,
+		"message":   expectedMsg.Message,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, res, err := nep413.ParseSignedMessageEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.AccountId != "idos.near" {
+		t.Fatalf("expected account id idos.near, got %s", res.AccountId)
+	}
+
+	if msg.CallbackUrl != nil {
+		t.Fatal("expected no callback url")
+	}
+
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = nep413.ParseSignedMessageEnvelope([]byte(`{"nonce": "abc"}`))
+	if err == nil {
+		t.Fatal("expected error for missing signedMessage")
+	}
+}
+
+func Test_DecodeNonce(t *testing.T) {
+	msg, res := testVector()
+
+	arrayForm, err := json.Marshal(msg.Nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encodings := map[string]string{
+		"byte array":     string(arrayForm),
+		"base64":         base64.StdEncoding.EncodeToString(msg.Nonce[:]),
+		"base64 url raw": base64.RawURLEncoding.EncodeToString(msg.Nonce[:]),
+		"base58":         base58.Encode(msg.Nonce[:]),
+	}
+
+	for name, encoded := range encodings {
+		t.Run(name, func(t *testing.T) {
+			nonce, err := nep413.DecodeNonce(encoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if nonce != msg.Nonce {
+				t.Fatalf("expected %v, got %v", msg.Nonce, nonce)
+			}
+
+			// the envelope parser accepts every encoding
+			nonceJSON := json.RawMessage(arrayForm)
+			if name != "byte array" {
+				nonceJSON, _ = json.Marshal(encoded)
+			}
+			data, _ := json.Marshal(map[string]any{
+				"signedMessage": res,
+				"nonce":         nonceJSON,
+				"recipient":     msg.Recipient,
+				"message":       msg.Message,
+			})
+			parsedMsg, parsedRes, err := nep413.ParseSignedMessageEnvelope(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := nep413.Verify(parsedMsg, parsedRes); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	if _, err := nep413.DecodeNonce("not a nonce"); !errors.Is(err, nep413.ErrInvalidNonceEncoding) {
+		t.Fatalf("expected ErrInvalidNonceEncoding, got %v", err)
+	}
+}
+
+func Test_ReconstructMessage(t *testing.T) {
+	expectedMsg, res := testVector()
+
+	msg := nep413.ReconstructMessage(res, expectedMsg.Recipient, expectedMsg.Message, expectedMsg.Nonce, "")
+	if msg.CallbackUrl != nil {
+		t.Fatal("expected no callback url")
+	}
+
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	// the tag is set, so the message also verifies with a preset tag
+	if err := nep413.Verify(msg, res, nep413.WithPresetTag()); err != nil {
+		t.Fatal(err)
+	}
+
+	msg = nep413.ReconstructMessage(res, expectedMsg.Recipient, expectedMsg.Message, expectedMsg.Nonce, "https://idos.network/callback")
+	if msg.CallbackUrl == nil || *msg.CallbackUrl != "https://idos.network/callback" {
+		t.Fatal("expected the callback url to be set")
+	}
+
+	if err := nep413.Verify(msg, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package nep413
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when an account has exceeded its verification rate limit.
+var ErrRateLimited = errors.New("too many verification attempts for account")
+
+// WithRateLimit limits verifications to perWindow per signer within window, failing with
+// ErrRateLimited when exceeded. The account id in a response is not signed, so only attempts
+// whose signature is valid are charged, and only once the account is bound to the signing key:
+//   - with WithAccessKeyCheck, or an implicit account derived by DeriveImplicitAccountID, the
+//     limit is per normalized account id
+//   - otherwise, the limit is per signing key, since any key may claim any account id
+//
+// Charging the claimed account unconditionally would let anyone lock an account out by
+// sending forged responses for it. Attempts with invalid signatures are not limited, so
+// limit those in front of the verifier, e.g. by client address, if needed.
+// The limiter's state lives in the option, so it should be passed to NewVerifier
+// (or reused across calls) rather than created per verification.
+func WithRateLimit(perWindow int, window time.Duration) VerifyOption {
+	limiter := newRateLimiter(perWindow, window)
+	return func(c *verifyConfig) {
+		c.limiter = limiter
+	}
+}
+
+// rateLimiter is a concurrency safe token bucket rate limiter, keyed by account id.
+// Each bucket holds up to perWindow tokens, and refills at perWindow tokens per window.
+type rateLimiter struct {
+	perWindow float64
+	window    time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(perWindow int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		perWindow: float64(perWindow),
+		window:    window,
+		buckets:   make(map[string]*tokenBucket),
+	}
+}
+
+// allow consumes a token for the key, returning false if there are none left.
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.perWindow, last: now}
+		l.buckets[key] = b
+	}
+
+	if l.window > 0 {
+		b.tokens += now.Sub(b.last).Seconds() * l.perWindow / l.window.Seconds()
+		if b.tokens > l.perWindow {
+			b.tokens = l.perWindow
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// sweep drops buckets that have been idle for a full window, since they
+// have refilled and are equivalent to a new bucket. It runs at most once per window.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= l.window {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package nep413_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+// keyViewer is an AccessKeyViewer that only knows the given key, on every account.
+type keyViewer struct {
+	key ed25519.PublicKey
+}
+
+func (k *keyViewer) ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*nep413.AccessKeyInfo, error) {
+	if !pub.Equal(k.key) {
+		return nil, nep413.ErrAccessKeyNotFound
+	}
+	return &nep413.AccessKeyInfo{FullAccess: true}, nil
+}
+
+func Test_WithRateLimit(t *testing.T) {
+	v := nep413.NewVerifier(nep413.WithRateLimit(2, time.Hour), nep413.WithAccessKeyCheck(&countingViewer{}))
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	for i := 0; i < 2; i++ {
+		if _, err := v.Verify(msg, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// account ids are normalized, so case changes do not bypass the limit
+	res.AccountId = "IDOS.near"
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited, got %v", err)
+	}
+
+	res.AccountId = "other.near"
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_RateLimitForgedResponses(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("with an access key check", func(t *testing.T) {
+		v := nep413.NewVerifier(nep413.WithRateLimit(1, time.Hour), nep413.WithAccessKeyCheck(&keyViewer{key: pub}))
+
+		// neither junk signatures nor valid signatures by a key not on the account are charged
+		junk := *res
+		junk.Signature = "Ni+rXvOtyzRr7X+qtvQ9+iJUu2e8L/e6cPjSzOYr+6W22chVnptTW0QqTUhFgKUbgPwd2tTcfB1D9Q+0Xb+sBw=="
+		forged, _ := testSign(t, msg)
+		forged.AccountId = "idos.near"
+		for i := 0; i < 3; i++ {
+			if _, err := v.Verify(msg, &junk); !errors.Is(err, nep413.ErrVerificationFailed) {
+				t.Fatalf("expected ErrVerificationFailed, got %v", err)
+			}
+			if _, err := v.Verify(msg, forged); !errors.Is(err, nep413.ErrAccessKeyNotFound) {
+				t.Fatalf("expected ErrAccessKeyNotFound, got %v", err)
+			}
+		}
+
+		if _, err := v.Verify(msg, res); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("without an access key check", func(t *testing.T) {
+		v := nep413.NewVerifier(nep413.WithRateLimit(1, time.Hour))
+
+		// the claimed account is not bound to the key, so the forger's own key is charged
+		forged, _ := testSign(t, msg)
+		forged.AccountId = "idos.near"
+		if _, err := v.Verify(msg, forged); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := v.Verify(msg, forged); !errors.Is(err, nep413.ErrRateLimited) {
+			t.Fatalf("expected ErrRateLimited, got %v", err)
+		}
+
+		if _, err := v.Verify(msg, res); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,189 @@
+package nep413
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	// ErrRecipientMismatch is returned when the signed recipient is not the expected one.
+	ErrRecipientMismatch = errors.New("signed recipient does not match expected recipient")
+	// ErrEmptyRecipient is returned when RejectEmptyRecipient is set and the signed recipient is empty.
+	ErrEmptyRecipient = errors.New("signed recipient is empty")
+)
+
+// WithExpectedRecipient fails verification with ErrRecipientMismatch unless the
+// signed recipient equals recipient, after both are normalized with NormalizeRecipient.
+// It replaces any previously expected recipients.
+func WithExpectedRecipient(recipient string) VerifyOption {
+	return WithExpectedRecipients(recipient)
+}
+
+// WithExpectedRecipients fails verification with ErrRecipientMismatch unless the signed
+// recipient equals any of recipients, after normalization. The matching recipient is
+// reported in VerifyResult.MatchedRecipient. It replaces any previously expected recipients.
+func WithExpectedRecipients(recipients ...string) VerifyOption {
+	return func(c *verifyConfig) {
+		c.expectedRecipients = recipients
+	}
+}
+
+// RejectEmptyRecipient fails verification with ErrEmptyRecipient if the signed recipient
+// is empty. An empty recipient defeats domain separation, and usually means the
+// client forgot to set it. It is included in VerifyStrict.
+func RejectEmptyRecipient() VerifyOption {
+	return func(c *verifyConfig) {
+		c.rejectEmptyRecipient = true
+	}
+}
+
+// TryTrimmedRecipient is an interop workaround for wallets that trim whitespace from the
+// recipient before signing: if the signature does not verify with the recipient as given,
+// it is tried again with the recipient trimmed. Which one was signed is reported in
+// VerifyResult.RecipientTrimmed, and the signed recipient is the one the nonce is consumed for.
+// With this option, expected recipients are also compared to the trimmed recipient.
+// Conforming wallets sign the recipient exactly, so by default it is not trimmed.
+func TryTrimmedRecipient() VerifyOption {
+	return func(c *verifyConfig) {
+		c.tryTrimmedRecipient = true
+	}
+}
+
+// VerifyExpectingRecipient verifies an NEP-413 signature, and checks that the message was
+// signed for the given recipient. This is useful for multi-tenant servers, where the
+// recipient differs per request.
+func VerifyExpectingRecipient(msg *Nep413Message, res *Nep413SignatureResponse, recipient string, opts ...VerifyOption) error {
+	return Verify(msg, res, append(opts, WithExpectedRecipient(recipient))...)
+}
+
+// VerifyExpectingAnyRecipient verifies an NEP-413 signature, and checks that the message was
+// signed for any of the given recipients. This is useful for deployments that serve several
+// recipient identities from one backend.
+func VerifyExpectingAnyRecipient(msg *Nep413Message, res *Nep413SignatureResponse, recipients []string, opts ...VerifyOption) error {
+	return Verify(msg, res, append(opts, WithExpectedRecipients(recipients...))...)
+}
+
+// WithExpectedRecipient makes every verification require the signed recipient to equal
+// recipient. This is the safe default for servers with a single application identity.
+// It must be called before the verifier is used. For a recipient that differs per request,
+// use Verifier.VerifyExpectingRecipient instead.
+func (v *Verifier) WithExpectedRecipient(recipient string) *Verifier {
+	if v.cfg == nil {
+		v.cfg = newVerifyConfig(nil)
+	}
+
+	WithExpectedRecipient(recipient)(v.cfg)
+	return v
+}
+
+// VerifyExpectingRecipient verifies an NEP-413 signature like VerifyContext, but requires the
+// signed recipient to equal recipient, in place of the verifier's expected recipients. This
+// is the verifier's counterpart to the package-level VerifyExpectingRecipient, for
+// multi-tenant servers, where the recipient differs per request. The hooks and Stats apply as
+// for VerifyContext, and the verifier itself is not modified.
+func (v *Verifier) VerifyExpectingRecipient(ctx context.Context, msg *Nep413Message, res *Nep413SignatureResponse, recipient string) (*VerifyResult, error) {
+	cfg := *v.config()
+	WithExpectedRecipient(recipient)(&cfg)
+
+	return v.verify(ctx, &cfg, msg, res)
+}
+
+// checkRecipient checks the signed recipient against the expected ones, if any,
+// returning the expected recipient that matched.
+func (c *verifyConfig) checkRecipient(msg *Nep413Message) (string, error) {
+	if c.rejectEmptyRecipient && msg.Recipient == "" {
+		return "", ErrEmptyRecipient
+	}
+
+	if c.expectedRecipients == nil {
+		return "", nil
+	}
+
+	signed := msg.Recipient
+	if c.tryTrimmedRecipient {
+		signed = strings.TrimSpace(signed)
+	}
+
+	signed = NormalizeRecipient(signed)
+	for _, expected := range c.expectedRecipients {
+		if signed == NormalizeRecipient(expected) {
+			return expected, nil
+		}
+	}
+
+	return "", ErrRecipientMismatch
+}
+
+// NormalizeRecipient returns the form of a recipient used for comparison. Recipients that
+// look like internationalized domains are converted to punycode, so that "café.app" and
+// "xn--caf-dma.app" are equal, and http(s) urls are normalized with NormalizeRecipientURL.
+// Valid NEAR account ids, and anything that is neither a domain nor a url, are returned
+// untouched.
+func NormalizeRecipient(s string) string {
+	if normalized, ok := normalizeRecipientURL(s); ok {
+		return normalized
+	}
+
+	if IsValidAccountID(s) || !strings.Contains(s, ".") || strings.ContainsAny(s, "/: ") {
+		return s
+	}
+
+	ascii, err := idna.Lookup.ToASCII(s)
+	if err != nil {
+		return s
+	}
+
+	return ascii
+}
+
+// NormalizeRecipientURL returns the form of a recipient that is an http or https url used
+// for comparison, so that insignificant differences do not cause mismatches: the scheme and
+// host are lowercased (and the host converted to punycode), the default port (80 for http,
+// 443 for https) is dropped, and so are trailing slashes of the path. The query and fragment
+// are kept as is. For example, "HTTPS://App.Example.com:443/" becomes
+// "https://app.example.com". Anything else is returned verbatim.
+func NormalizeRecipientURL(s string) string {
+	normalized, _ := normalizeRecipientURL(s)
+	return normalized
+}
+
+// normalizeRecipientURL normalizes s if it is an http or https url, and reports whether it is.
+func normalizeRecipientURL(s string) (string, bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || u.User != nil || u.Opaque != "" {
+		return s, false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return s, false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if ascii, err := idna.Lookup.ToASCII(host); err == nil {
+		host = ascii
+	}
+
+	port := u.Port()
+	if (scheme == "http" && port == "80") || (scheme == "https" && port == "443") {
+		port = ""
+	}
+
+	normalized := url.URL{
+		Scheme:   scheme,
+		Host:     host,
+		Path:     strings.TrimRight(u.Path, "/"),
+		RawQuery: u.RawQuery,
+		Fragment: u.Fragment,
+	}
+	if port != "" {
+		normalized.Host = net.JoinHostPort(host, port)
+	}
+
+	return normalized.String(), true
+}
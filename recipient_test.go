@@ -0,0 +1,192 @@
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_ExpectedRecipient(t *testing.T) {
+	msg, res := testVector()
+
+	if err := nep413.VerifyExpectingRecipient(msg, res, "idos.network"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := nep413.VerifyExpectingRecipient(msg, res, "attacker.near")
+	if !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+
+	v := nep413.NewVerifier().WithExpectedRecipient("idos.network")
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	v = nep413.NewVerifier().WithExpectedRecipient("other.app")
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+}
+
+func Test_VerifierExpectingRecipient(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	// the per-call recipient replaces the verifier's, and the rest of its policy still applies
+	v := nep413.NewVerifier(nep413.WithExpectedRecipient("other.app"), nep413.WithAllowedAccounts("idos.near"))
+	result, err := v.VerifyExpectingRecipient(context.Background(), msg, res, "idos.network")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchedRecipient != "idos.network" {
+		t.Fatalf("expected matched recipient idos.network, got %s", result.MatchedRecipient)
+	}
+
+	if _, err := v.VerifyExpectingRecipient(context.Background(), msg, res, "attacker.near"); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+
+	other := *res
+	other.AccountId = "attacker.near"
+	if _, err := v.VerifyExpectingRecipient(context.Background(), msg, &other, "idos.network"); !errors.Is(err, nep413.ErrAccountNotAllowed) {
+		t.Fatalf("expected ErrAccountNotAllowed, got %v", err)
+	}
+
+	// the verifier itself is unchanged
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+	if stats := v.Stats.Snapshot(); stats.Attempted != 4 || stats.Succeeded != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func Test_RejectEmptyRecipient(t *testing.T) {
+	msg, _ := testVector()
+	msg.Recipient = ""
+	res, _ := testSign(t, msg)
+
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opt := range []nep413.VerifyOption{nep413.RejectEmptyRecipient(), nep413.VerifyStrict()} {
+		if err := nep413.Verify(msg, res, opt); !errors.Is(err, nep413.ErrEmptyRecipient) {
+			t.Fatalf("expected ErrEmptyRecipient, got %v", err)
+		}
+	}
+}
+
+func Test_NormalizeRecipient(t *testing.T) {
+	tests := map[string]string{
+		"café.app":        "xn--caf-dma.app",
+		"xn--caf-dma.app": "xn--caf-dma.app",
+		"CAFÉ.app":        "xn--caf-dma.app",
+		"idos.near":       "idos.near",
+		"Idos.near":       "idos.near",
+		"not a domain":    "not a domain",
+	}
+
+	for recipient, expected := range tests {
+		if normalized := nep413.NormalizeRecipient(recipient); normalized != expected {
+			t.Errorf("expected %s to normalize to %s, got %s", recipient, expected, normalized)
+		}
+	}
+}
+
+func Test_ExpectedRecipientIDN(t *testing.T) {
+	msg, _ := testVector()
+	msg.Recipient = "café.app"
+	res, _ := testSign(t, msg)
+
+	if err := nep413.VerifyExpectingRecipient(msg, res, "xn--caf-dma.app"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ExpectingAnyRecipient(t *testing.T) {
+	msg, res := testVector()
+
+	if err := nep413.VerifyExpectingAnyRecipient(msg, res, []string{"other.app", "idos.network"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := nep413.VerifyExpectingAnyRecipient(msg, res, []string{"other.app", "another.app"})
+	if !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+
+	result, err := nep413.NewVerifier(nep413.WithExpectedRecipients("other.app", "idos.network")).Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchedRecipient != "idos.network" {
+		t.Fatalf("expected matched recipient idos.network, got %s", result.MatchedRecipient)
+	}
+}
+
+func Test_TryTrimmedRecipient(t *testing.T) {
+	msg, res := testVector()
+
+	// the server reconstructs the message with whitespace around the recipient
+	untrimmed := *msg
+	untrimmed.Recipient = " idos.network\n"
+
+	if err := nep413.Verify(&untrimmed, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	v := nep413.NewVerifier(nep413.TryTrimmedRecipient(), nep413.WithExpectedRecipient("idos.network"))
+	result, err := v.Verify(&untrimmed, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.RecipientTrimmed || result.Recipient != "idos.network" {
+		t.Fatalf("expected the trimmed recipient to match, got %q", result.Recipient)
+	}
+	if untrimmed.Recipient != " idos.network\n" {
+		t.Fatal("expected the message to be unchanged")
+	}
+
+	// the exact recipient is tried first
+	result, err = v.Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RecipientTrimmed {
+		t.Fatal("expected the exact recipient to match")
+	}
+}
+
+func Test_NormalizeRecipientURL(t *testing.T) {
+	tests := map[string]string{
+		"https://app.example.com":               "https://app.example.com",
+		"https://app.example.com/":              "https://app.example.com",
+		"HTTPS://App.Example.com:443/":          "https://app.example.com",
+		"http://app.example.com:80/login//":     "http://app.example.com/login",
+		"https://app.example.com:8443/":         "https://app.example.com:8443",
+		"http://app.example.com:443":            "http://app.example.com:443",
+		"https://app.example.com/?next=/login/": "https://app.example.com?next=/login/",
+		"app.example.com/":                      "app.example.com/",
+		"idos.near":                             "idos.near",
+	}
+
+	for recipient, expected := range tests {
+		if normalized := nep413.NormalizeRecipientURL(recipient); normalized != expected {
+			t.Errorf("expected %s to normalize to %s, got %s", recipient, expected, normalized)
+		}
+	}
+
+	msg, _ := testVector()
+	msg.Recipient = "https://app.example.com/"
+	res, _ := testSign(t, msg)
+	if err := nep413.VerifyExpectingRecipient(msg, res, "https://app.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.VerifyExpectingRecipient(msg, res, "https://evil.example.com"); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,386 @@
+//go:build !(js && wasm)
+
+package nep413
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultRPCConcurrency is the number of concurrent requests made by ViewAccessKeys.
+const defaultRPCConcurrency = 8
+
+// defaultMaxResponseBytes is the default limit on the size of an RPC response body. Access
+// key views are tiny, but Query results such as view_state can be large.
+const defaultMaxResponseBytes = 16 << 20
+
+// ErrResponseTooLarge is returned when an RPC response body exceeds the client's limit
+// (see WithMaxResponseBytes).
+var ErrResponseTooLarge = errors.New("rpc response too large")
+
+// RPCClient queries a NEAR JSON-RPC endpoint, e.g. https://rpc.mainnet.near.org.
+// It is safe for concurrent use.
+type RPCClient struct {
+	url         string
+	httpClient  *http.Client
+	concurrency int
+	requestID   atomic.Uint64
+	// retries is the number of times a failed request is retried
+	retries int
+	// retryBackoff is the delay before the first retry, doubled for every further retry
+	retryBackoff time.Duration
+	// maxResponseBytes is the maximum size of a response body
+	maxResponseBytes int64
+}
+
+var (
+	_ AccessKeyViewer = (*RPCClient)(nil)
+	_ AccessKeyLister = (*RPCClient)(nil)
+)
+
+// RPCOption configures an RPCClient.
+type RPCOption func(*RPCClient)
+
+// WithHTTPClient sets the http client used for requests. The default is http.DefaultClient.
+func WithHTTPClient(client *http.Client) RPCOption {
+	return func(c *RPCClient) {
+		c.httpClient = client
+	}
+}
+
+// WithConcurrency sets the maximum number of concurrent requests made by ViewAccessKeys.
+func WithConcurrency(n int) RPCOption {
+	return func(c *RPCClient) {
+		c.concurrency = n
+	}
+}
+
+// WithRetries retries requests that fail with a network error or a server error status
+// (429 or 5xx) up to retries times, waiting backoff before the first retry, and doubling
+// it for every further one. Errors reported by the node, such as an unknown access key,
+// are not retried. The default is no retries.
+func WithRetries(retries int, backoff time.Duration) RPCOption {
+	return func(c *RPCClient) {
+		c.retries = retries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithMaxResponseBytes limits the size of response bodies read from the endpoint to n bytes,
+// so that a hostile or broken endpoint cannot exhaust memory. Larger responses fail with
+// ErrResponseTooLarge, and are not retried. The default is 16 MiB.
+func WithMaxResponseBytes(n int64) RPCOption {
+	return func(c *RPCClient) {
+		c.maxResponseBytes = n
+	}
+}
+
+// NewRPCClient creates a client for the NEAR JSON-RPC endpoint at url.
+func NewRPCClient(url string, opts ...RPCOption) *RPCClient {
+	c := &RPCClient{
+		url:              url,
+		httpClient:       http.DefaultClient,
+		concurrency:      defaultRPCConcurrency,
+		maxResponseBytes: defaultMaxResponseBytes,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+
+	return c
+}
+
+// CloseIdleConnections closes the idle connections of the client's http client, e.g. on
+// shutdown. The client can still be used afterwards.
+func (c *RPCClient) CloseIdleConnections() {
+	c.httpClient.CloseIdleConnections()
+}
+
+// accessKeyView is the result of the view_access_key query.
+type accessKeyView struct {
+	Nonce       uint64          `json:"nonce"`
+	Permission  json.RawMessage `json:"permission"`
+	BlockHeight uint64          `json:"block_height"`
+	BlockHash   string          `json:"block_hash"`
+	// Error is set by older nodes, which report query errors inside the result
+	Error string `json:"error"`
+}
+
+// ViewAccessKey looks up the public key on the account at the latest final block.
+// It returns ErrAccessKeyNotFound if the key is not an access key of the account.
+func (c *RPCClient) ViewAccessKey(ctx context.Context, accountID string, pub ed25519.PublicKey) (*AccessKeyInfo, error) {
+	var view accessKeyView
+	err := c.call(ctx, "query", map[string]any{
+		"request_type": "view_access_key",
+		"finality":     "final",
+		"account_id":   accountID,
+		"public_key":   FormatPublicKey(pub),
+	}, &view)
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Cause == "UNKNOWN_ACCESS_KEY" {
+		return nil, ErrAccessKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if view.Error != "" {
+		if strings.Contains(view.Error, "does not exist") {
+			return nil, ErrAccessKeyNotFound
+		}
+		return nil, fmt.Errorf("rpc query error: %s", view.Error)
+	}
+
+	functionCall, err := parseAccessKeyPermission(view.Permission)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AccessKeyInfo{
+		Nonce:        view.Nonce,
+		BlockHeight:  view.BlockHeight,
+		BlockHash:    view.BlockHash,
+		FullAccess:   functionCall == nil,
+		FunctionCall: functionCall,
+	}, nil
+}
+
+// accessKeyListView is the result of the view_access_key_list query.
+type accessKeyListView struct {
+	Keys []struct {
+		PublicKey string        `json:"public_key"`
+		AccessKey accessKeyView `json:"access_key"`
+	} `json:"keys"`
+	BlockHeight uint64 `json:"block_height"`
+	BlockHash   string `json:"block_hash"`
+	// Error is set by older nodes, which report query errors inside the result
+	Error string `json:"error"`
+}
+
+// ViewAccessKeyList lists the access keys of the account at the latest final block. Keys of
+// other types than ed25519 are left out. An unknown account is reported as an *RPCError.
+func (c *RPCClient) ViewAccessKeyList(ctx context.Context, accountID string) ([]AccountAccessKey, error) {
+	var view accessKeyListView
+	err := c.call(ctx, "query", map[string]any{
+		"request_type": "view_access_key_list",
+		"finality":     "final",
+		"account_id":   accountID,
+	}, &view)
+	if err != nil {
+		return nil, err
+	}
+
+	if view.Error != "" {
+		return nil, fmt.Errorf("rpc query error: %s", view.Error)
+	}
+
+	keys := make([]AccountAccessKey, 0, len(view.Keys))
+	for _, key := range view.Keys {
+		if keyType, err := KeyTypeOf(key.PublicKey); err == nil && keyType != KeyTypeED25519 {
+			continue
+		}
+
+		pub, err := ParsePublicKey(key.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid access key %q: %w", key.PublicKey, err)
+		}
+
+		functionCall, err := parseAccessKeyPermission(key.AccessKey.Permission)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, AccountAccessKey{
+			PublicKey: pub,
+			Info: &AccessKeyInfo{
+				Nonce:        key.AccessKey.Nonce,
+				BlockHeight:  view.BlockHeight,
+				BlockHash:    view.BlockHash,
+				FullAccess:   functionCall == nil,
+				FunctionCall: functionCall,
+			},
+		})
+	}
+
+	return keys, nil
+}
+
+// KeyQuery identifies an access key to look up.
+type KeyQuery struct {
+	AccountId string
+	PublicKey ed25519.PublicKey
+}
+
+// AccessKeyResult is the result of a single KeyQuery. Exactly one of Info and Err is set.
+type AccessKeyResult struct {
+	Info *AccessKeyInfo
+	Err  error
+}
+
+// ViewAccessKeys looks up many access keys concurrently, with at most the
+// client's concurrency limit of requests in flight. The results are aligned
+// with the queries. Each query is bound by ctx; if ctx is done before all
+// queries complete, the remaining results are set to the context's error,
+// and it is also returned.
+func (c *RPCClient) ViewAccessKeys(ctx context.Context, queries []KeyQuery) ([]AccessKeyResult, error) {
+	results := make([]AccessKeyResult, len(queries))
+	sem := make(chan struct{}, c.concurrency)
+
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			for j := i; j < len(queries); j++ {
+				results[j].Err = ctx.Err()
+			}
+			wg.Wait()
+			return results, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(i int, query KeyQuery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i].Info, results[i].Err = c.ViewAccessKey(ctx, query.AccountId, query.PublicKey)
+		}(i, query)
+	}
+	wg.Wait()
+
+	return results, ctx.Err()
+}
+
+// Query performs a JSON-RPC "query" call with the given params (e.g. a view_state request),
+// returning the raw result to be decoded by the caller. JSON-RPC errors are returned as *RPCError.
+func (c *RPCClient) Query(ctx context.Context, params map[string]any) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := c.call(ctx, "query", params, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RPCError is a JSON-RPC error object returned by a NEAR node.
+type RPCError struct {
+	// Code is the JSON-RPC error code
+	Code int
+	// Message is the JSON-RPC error message
+	Message string
+	// Name is NEAR's error category, e.g. "HANDLER_ERROR"
+	Name string
+	// Cause is NEAR's specific error, e.g. "UNKNOWN_ACCESS_KEY"
+	Cause string
+	// Data is the raw error data, if any
+	Data json.RawMessage
+}
+
+func (e *RPCError) Error() string {
+	if e.Cause != "" {
+		return fmt.Sprintf("rpc error %d: %s (%s: %s)", e.Code, e.Message, e.Name, e.Cause)
+	}
+
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// rpcResponse is a JSON-RPC 2.0 response.
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+		Name    string          `json:"name"`
+		Cause   struct {
+			Name string `json:"name"`
+		} `json:"cause"`
+	} `json:"error"`
+}
+
+// call performs a JSON-RPC call, decoding the result into result, and retrying
+// transient failures as configured with WithRetries.
+func (c *RPCClient) call(ctx context.Context, method string, params any, result any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      c.requestID.Add(1),
+		"method":  method,
+		"params":  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := c.post(ctx, body, result)
+		if err == nil || !retryable || attempt >= c.retries || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+		backoff *= 2
+	}
+}
+
+// post sends a JSON-RPC request, decoding the result into result. It reports whether a
+// failure is transient, and so may be retried.
+func (c *RPCClient) post(ctx context.Context, body []byte, result any) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+	// read one byte past the limit, to tell a response of exactly the limit from a larger one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxResponseBytes+1))
+	if err != nil {
+		return true, err
+	}
+	if int64(len(data)) > c.maxResponseBytes {
+		return false, fmt.Errorf("%w: exceeds %d bytes", ErrResponseTooLarge, c.maxResponseBytes)
+	}
+
+	var rpcRes rpcResponse
+	if err := json.Unmarshal(data, &rpcRes); err != nil {
+		return retryable, fmt.Errorf("invalid rpc response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if rpcRes.Error != nil {
+		return retryable, &RPCError{
+			Code:    rpcRes.Error.Code,
+			Message: rpcRes.Error.Message,
+			Name:    rpcRes.Error.Name,
+			Cause:   rpcRes.Error.Cause.Name,
+			Data:    rpcRes.Error.Data,
+		}
+	}
+
+	return false, json.Unmarshal(rpcRes.Result, result)
+}
@@ -0,0 +1,276 @@
+//go:build !(js && wasm)
+
+package nep413_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+// newTestRPC starts a fake NEAR RPC server that knows the given access keys,
+// keyed by account id and then NEAR formatted public key.
+func newTestRPC(t *testing.T, keys map[string]map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64            `json:"id"`
+			Params map[string]string `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		permission, ok := keys[req.Params["account_id"]][req.Params["public_key"]]
+		if req.Params["request_type"] == "view_state" {
+			res["result"] = map[string]any{"values": []any{}, "block_height": 1000}
+		} else if req.Params["request_type"] == "view_access_key_list" {
+			list := []any{}
+			for publicKey, permission := range keys[req.Params["account_id"]] {
+				list = append(list, map[string]any{
+					"public_key": publicKey,
+					"access_key": map[string]any{"nonce": 42, "permission": json.RawMessage(permission)},
+				})
+			}
+			res["result"] = map[string]any{"keys": list, "block_height": 1000, "block_hash": "hash"}
+		} else if ok {
+			res["result"] = map[string]any{
+				"nonce":        42,
+				"permission":   json.RawMessage(permission),
+				"block_height": 1000,
+				"block_hash":   "hash",
+			}
+		} else {
+			res["error"] = map[string]any{
+				"code":    -32000,
+				"message": "Server error",
+				"name":    "HANDLER_ERROR",
+				"cause":   map[string]any{"name": "UNKNOWN_ACCESS_KEY"},
+			}
+		}
+
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func Test_ViewAccessKeys(t *testing.T) {
+	fullKey, _, _ := ed25519.GenerateKey(nil)
+	callKey, _, _ := ed25519.GenerateKey(nil)
+	unknownKey, _, _ := ed25519.GenerateKey(nil)
+
+	server := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {
+			nep413.FormatPublicKey(fullKey): `"FullAccess"`,
+			nep413.FormatPublicKey(callKey): `{"FunctionCall": {"allowance": null, "receiver_id": "idos.near", "method_names": []}}`,
+		},
+	})
+
+	client := nep413.NewRPCClient(server.URL, nep413.WithConcurrency(2))
+	results, err := client.ViewAccessKeys(context.Background(), []nep413.KeyQuery{
+		{AccountId: "idos.near", PublicKey: fullKey},
+		{AccountId: "idos.near", PublicKey: callKey},
+		{AccountId: "idos.near", PublicKey: unknownKey},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if results[0].Err != nil || !results[0].Info.FullAccess || results[0].Info.FunctionCall != nil || results[0].Info.Nonce != 42 {
+		t.Fatalf("unexpected result for full access key: %+v", results[0])
+	}
+
+	if results[1].Err != nil || results[1].Info.FullAccess {
+		t.Fatalf("unexpected result for function call key: %+v", results[1])
+	}
+
+	if !errors.Is(results[2].Err, nep413.ErrAccessKeyNotFound) {
+		t.Fatalf("expected ErrAccessKeyNotFound, got %v", results[2].Err)
+	}
+}
+
+func Test_RPCQuery(t *testing.T) {
+	server := newTestRPC(t, nil)
+	client := nep413.NewRPCClient(server.URL)
+
+	result, err := client.Query(context.Background(), map[string]any{
+		"request_type":  "view_state",
+		"finality":      "final",
+		"account_id":    "idos.near",
+		"prefix_base64": "",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state struct {
+		BlockHeight uint64 `json:"block_height"`
+	}
+	if err := json.Unmarshal(result, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.BlockHeight != 1000 {
+		t.Fatalf("unexpected result %s", result)
+	}
+
+	_, err = client.Query(context.Background(), map[string]any{
+		"request_type": "view_access_key",
+		"account_id":   "idos.near",
+	})
+	var rpcErr *nep413.RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Cause != "UNKNOWN_ACCESS_KEY" {
+		t.Fatalf("expected RPCError, got %v", err)
+	}
+}
+
+func Test_RPCRetries(t *testing.T) {
+	_, res := testVector()
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backend := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {res.PublicKey: `"FullAccess"`},
+	})
+
+	// the node is unavailable for the first two requests
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) <= 2 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		resp, err := http.Post(backend.URL, "application/json", r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(w, resp.Body)
+	}))
+	defer server.Close()
+
+	client := nep413.NewRPCClient(server.URL, nep413.WithRetries(2, time.Millisecond))
+	if _, err := client.ViewAccessKey(context.Background(), "idos.near", pub); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests.Load())
+	}
+
+	// node errors are not retried
+	requests.Store(2)
+	if _, err := client.ViewAccessKey(context.Background(), "other.near", pub); !errors.Is(err, nep413.ErrAccessKeyNotFound) {
+		t.Fatalf("expected ErrAccessKeyNotFound, got %v", err)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("expected 1 request, got %d", requests.Load()-2)
+	}
+
+	// without retries, the first failure is returned
+	requests.Store(0)
+	if _, err := nep413.NewRPCClient(server.URL).ViewAccessKey(context.Background(), "idos.near", pub); err == nil {
+		t.Fatal("expected error without retries")
+	}
+}
+
+func Test_RPCMaxResponseBytes(t *testing.T) {
+	_, res := testVector()
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {res.PublicKey: `"FullAccess"`},
+	})
+
+	if _, err := nep413.NewRPCClient(server.URL).ViewAccessKey(context.Background(), "idos.near", pub); err != nil {
+		t.Fatal(err)
+	}
+
+	client := nep413.NewRPCClient(server.URL, nep413.WithMaxResponseBytes(16))
+	if _, err := client.ViewAccessKey(context.Background(), "idos.near", pub); !errors.Is(err, nep413.ErrResponseTooLarge) {
+		t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+	}
+}
+
+func Test_FunctionCallPermission(t *testing.T) {
+	limitedKey, _, _ := ed25519.GenerateKey(nil)
+	unlimitedKey, _, _ := ed25519.GenerateKey(nil)
+
+	server := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {
+			nep413.FormatPublicKey(limitedKey):   `{"FunctionCall": {"allowance": "250000000000000000000000", "receiver_id": "app.idos.near", "method_names": ["login"]}}`,
+			nep413.FormatPublicKey(unlimitedKey): `{"FunctionCall": {"allowance": null, "receiver_id": "app.idos.near", "method_names": []}}`,
+		},
+	})
+	client := nep413.NewRPCClient(server.URL)
+
+	limited, err := client.ViewAccessKey(context.Background(), "idos.near", limitedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	permission := limited.FunctionCall
+	if limited.FullAccess || permission == nil || permission.Allowance == nil || permission.Allowance.String() != "250000000000000000000000" {
+		t.Fatalf("unexpected access key %+v", limited)
+	}
+	if !permission.Allows("app.idos.near", "login") || permission.Allows("app.idos.near", "transfer") || permission.Allows("other.near", "login") {
+		t.Fatalf("unexpected permission %+v", permission)
+	}
+
+	unlimited, err := client.ViewAccessKey(context.Background(), "idos.near", unlimitedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unlimited.FunctionCall == nil || unlimited.FunctionCall.Allowance != nil || !unlimited.FunctionCall.Allows("app.idos.near", "transfer") {
+		t.Fatalf("unexpected access key %+v", unlimited)
+	}
+}
+
+func Test_ViewAccessKeyList(t *testing.T) {
+	fullKey, _, _ := ed25519.GenerateKey(nil)
+	callKey, _, _ := ed25519.GenerateKey(nil)
+	_, secp256k1Res := testSecp256k1Vector()
+
+	server := newTestRPC(t, map[string]map[string]string{
+		"idos.near": {
+			nep413.FormatPublicKey(fullKey): `"FullAccess"`,
+			nep413.FormatPublicKey(callKey): `{"FunctionCall": {"allowance": null, "receiver_id": "idos.near", "method_names": []}}`,
+			secp256k1Res.PublicKey:          `"FullAccess"`,
+		},
+	})
+	client := nep413.NewRPCClient(server.URL)
+
+	keys, err := client.ViewAccessKeyList(context.Background(), "idos.near")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("expected the 2 ed25519 keys, got %d", len(keys))
+	}
+	for _, key := range keys {
+		if key.Info.FullAccess != key.PublicKey.Equal(fullKey) || key.Info.Nonce != 42 || key.Info.BlockHeight != 1000 {
+			t.Fatalf("unexpected access key %s: %+v", nep413.FormatPublicKey(key.PublicKey), key.Info)
+		}
+	}
+
+	v := nep413.NewVerifier(nep413.WithAccessKeyCheck(client), nep413.WithAccessKeyCache(time.Hour))
+	if err := v.WarmAccessKeys(context.Background(), []string{"idos.near"}); err != nil {
+		t.Fatal(err)
+	}
+}
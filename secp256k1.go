@@ -0,0 +1,191 @@
+package nep413
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"github.com/mr-tron/base58"
+)
+
+// KeyType is the signature algorithm of a NEAR public key, given by the prefix of its
+// string form, e.g. "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg".
+type KeyType uint8
+
+const (
+	// KeyTypeED25519 is an ed25519 key, which almost all NEAR accounts use.
+	KeyTypeED25519 KeyType = iota
+	// KeyTypeSECP256K1 is a secp256k1 key, which NEAR also supports, e.g. for keys
+	// shared with Ethereum.
+	KeyTypeSECP256K1
+)
+
+// String returns the key type as it prefixes NEAR keys, e.g. "ed25519".
+func (k KeyType) String() string {
+	switch k {
+	case KeyTypeED25519:
+		return "ed25519"
+	case KeyTypeSECP256K1:
+		return "secp256k1"
+	default:
+		return fmt.Sprintf("KeyType(%d)", uint8(k))
+	}
+}
+
+// ErrUnsupportedKeyType is returned when a public key's algorithm is neither ed25519 nor secp256k1.
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
+
+// KeyTypeOf returns the type of a NEAR formatted public key. Only the prefix is read,
+// so the key itself may still be malformed.
+func KeyTypeOf(s string) (KeyType, error) {
+	algorithm, _, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, errors.New("invalid public key format, expected algorithm:base58_encoded_public_key")
+	}
+
+	switch algorithm {
+	case "ed25519":
+		return KeyTypeED25519, nil
+	case "secp256k1":
+		return KeyTypeSECP256K1, nil
+	default:
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedKeyType, algorithm)
+	}
+}
+
+// ErrDisallowedKeyType is returned when RequireKeyType is set and the response is signed with
+// a key of another type.
+var ErrDisallowedKeyType = errors.New("key type is not allowed")
+
+// RequireKeyType fails verification with ErrDisallowedKeyType unless the signing key is of one
+// of the given types, e.g. RequireKeyType(KeyTypeED25519) for a policy that forbids secp256k1
+// signers. The key type is checked before the key is parsed. By default, every supported key
+// type is accepted.
+func RequireKeyType(types ...KeyType) VerifyOption {
+	return func(c *verifyConfig) {
+		c.allowedKeyTypes = types
+	}
+}
+
+// checkKeyType checks the type of the signing key against the allowed ones, if any.
+func (c *verifyConfig) checkKeyType(keyType KeyType) error {
+	if c.allowedKeyTypes != nil && !slices.Contains(c.allowedKeyTypes, keyType) {
+		return fmt.Errorf("%w: %s", ErrDisallowedKeyType, keyType)
+	}
+
+	return nil
+}
+
+// secp256k1KeySize is the size of a secp256k1 key as NEAR encodes it: the uncompressed
+// point, without its leading 0x04.
+const secp256k1KeySize = 64
+
+// ParseSecp256k1PublicKey parses a secp256k1 public key in NEAR's format: "secp256k1:"
+// followed by the 64 byte uncompressed point (without its 0x04 prefix), base58 encoded.
+func ParseSecp256k1PublicKey(s string) (*secp256k1.PublicKey, error) {
+	encoded, ok := strings.CutPrefix(s, "secp256k1:")
+	if !ok {
+		return nil, errors.New("invalid public key format, expected secp256k1:base58_encoded_public_key")
+	}
+
+	pubkeyBytes, err := decodeBase58(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pubkeyBytes) != secp256k1KeySize {
+		return nil, fmt.Errorf("invalid public key length, expected %d, got %d", secp256k1KeySize, len(pubkeyBytes))
+	}
+
+	pub, err := secp256k1.ParsePubKey(append([]byte{0x04}, pubkeyBytes...))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secp256k1 public key: %w", err)
+	}
+
+	return pub, nil
+}
+
+// FormatSecp256k1PublicKey formats a secp256k1 public key the way NEAR does.
+func FormatSecp256k1PublicKey(pub *secp256k1.PublicKey) string {
+	return "secp256k1:" + base58.EncodeAlphabet(pub.SerializeUncompressed()[1:], nearAlphabet)
+}
+
+// VerifySecp256k1Hash verifies a secp256k1 signature over a precomputed NEP-413 payload
+// hash, like VerifyHash. The signature is r||s, 64 bytes, optionally followed by the
+// recovery id, as NEAR serializes secp256k1 signatures; the recovery id is not needed, as
+// the key is known, but must be 0 or 1 (or 27 or 28, as Ethereum offsets it). Signatures
+// with a high S value are rejected, as NEAR and Bitcoin do, so that every signature has a
+// single valid encoding.
+func VerifySecp256k1Hash(hash [32]byte, sig []byte, pub *secp256k1.PublicKey) error {
+	r, s, err := parseSecp256k1Signature(sig)
+	if err != nil {
+		return err
+	}
+
+	if !ecdsa.NewSignature(r, s).Verify(hash[:], pub) {
+		return ErrVerificationFailed
+	}
+
+	return nil
+}
+
+// parseSecp256k1Signature parses r||s, and the optional recovery id, of a secp256k1
+// signature, rejecting anything but the canonical low S form.
+func parseSecp256k1Signature(sig []byte) (*secp256k1.ModNScalar, *secp256k1.ModNScalar, error) {
+	switch {
+	case len(sig) == 64:
+	case len(sig) == 65 && (sig[64] <= 1 || sig[64] == 27 || sig[64] == 28):
+	case len(sig) == 65:
+		return nil, nil, fmt.Errorf("%w: invalid recovery id %d", ErrVerificationFailed, sig[64])
+	default:
+		return nil, nil, fmt.Errorf("%w: invalid signature length, expected 64 or 65, got %d", ErrVerificationFailed, len(sig))
+	}
+
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:64]) {
+		return nil, nil, fmt.Errorf("%w: signature is not in the curve order", ErrVerificationFailed)
+	}
+	if s.IsOverHalfOrder() {
+		return nil, nil, fmt.Errorf("%w: signature has a high S value", ErrVerificationFailed)
+	}
+
+	return &r, &s, nil
+}
+
+// canonicalSecp256k1Signature returns the 64 byte r||s of a valid secp256k1 signature,
+// without its recovery id, which is what identifies the signature in a SignatureStore.
+func canonicalSecp256k1Signature(sig []byte) ([]byte, bool) {
+	if _, _, err := parseSecp256k1Signature(sig); err != nil {
+		return nil, false
+	}
+
+	return sig[:64], true
+}
+
+// matchSecp256k1 verifies the signature of msg against a secp256k1 key. The signature is
+// always over the sha256 hash of the payload, as ECDSA signs a digest; WithPayloadHashing
+// only applies to ed25519 keys.
+func (c *verifyConfig) matchSecp256k1(msg *Nep413Message, res *Nep413SignatureResponse, pub *secp256k1.PublicKey) (*signatureMatch, error) {
+	decodedSignature, err := c.decodeSignature(res.Signature, KeyTypeSECP256K1)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.payload(msg)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(payload)
+
+	if VerifySecp256k1Hash(hash, decodedSignature, pub) != nil {
+		return nil, ErrNoMatchingKey
+	}
+
+	// the recovery id is left out, so that appending one does not bypass the signature store
+	signature, _ := canonicalSecp256k1Signature(decodedSignature)
+	return &signatureMatch{secp256k1Key: pub, signature: signature, hash: hash, msg: msg}, nil
+}
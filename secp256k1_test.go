@@ -0,0 +1,171 @@
+package nep413_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/mr-tron/base58"
+)
+
+// testSecp256k1Vector returns testVector's message, signed with a secp256k1 key derived from
+// sha256("nep413 secp256k1 test vector").
+func testSecp256k1Vector() (*nep413.Nep413Message, *nep413.Nep413SignatureResponse) {
+	msg, _ := testVector()
+
+	res := &nep413.Nep413SignatureResponse{
+		Signature: "keddtS/CLs6bLgK7X1PJLvfUCYEwPyHc4BpLGQUjUfY6PQuMYhaGMy5q7KbdKT9qwD2nb35911DRd0k7YAOaoQ==",
+		PublicKey: "secp256k1:5SpGmmtnd7hR76FJMJnrdRjWoeVTr48HAf75TUorfasiFfs143wnp36gEoDHb3hk17NLN2sFeb41ib87bwjoUUAF",
+	}
+
+	return msg, res
+}
+
+func Test_VerifySecp256k1(t *testing.T) {
+	msg, res := testSecp256k1Vector()
+
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := nep413.NewVerifier().Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.KeyType != nep413.KeyTypeSECP256K1 || result.NearKey != res.PublicKey || result.Secp256k1PublicKey == nil {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	// NEAR appends the recovery id to secp256k1 signatures
+	withRecovery := *res
+	withRecovery.Signature = "keddtS/CLs6bLgK7X1PJLvfUCYEwPyHc4BpLGQUjUfY6PQuMYhaGMy5q7KbdKT9qwD2nb35911DRd0k7YAOaoQA="
+	if err := nep413.Verify(msg, &withRecovery); err != nil {
+		t.Fatal(err)
+	}
+
+	// as may NEAR's prefixed base58 encoding
+	signature, err := base64.StdEncoding.DecodeString(withRecovery.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefixed := *res
+	prefixed.Signature = "secp256k1:" + base58.Encode(signature)
+	if err := nep413.Verify(msg, &prefixed); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := *msg
+	tampered.Message = "something else"
+	if err := nep413.Verify(&tampered, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	// access keys can only be looked up for ed25519 keys, so the check fails closed
+	withAccount := *res
+	withAccount.AccountId = "idos.near"
+	viewer := &countingViewer{}
+	if err := nep413.Verify(msg, &withAccount, nep413.WithAccessKeyCheck(viewer)); err == nil || viewer.lookups.Load() != 0 {
+		t.Fatalf("expected the access key check to fail without a lookup, got %v", err)
+	}
+}
+
+func Test_KeyTypeOf(t *testing.T) {
+	_, ed := testVector()
+	_, secp := testSecp256k1Vector()
+
+	if keyType, err := nep413.KeyTypeOf(ed.PublicKey); err != nil || keyType != nep413.KeyTypeED25519 {
+		t.Fatalf("expected ed25519, got %v (%v)", keyType, err)
+	}
+	if keyType, err := nep413.KeyTypeOf(secp.PublicKey); err != nil || keyType != nep413.KeyTypeSECP256K1 {
+		t.Fatalf("expected secp256k1, got %v (%v)", keyType, err)
+	}
+
+	unknown := *ed
+	unknown.PublicKey = "bls12381:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg"
+	if _, err := nep413.KeyTypeOf(unknown.PublicKey); !errors.Is(err, nep413.ErrUnsupportedKeyType) {
+		t.Fatalf("expected ErrUnsupportedKeyType, got %v", err)
+	}
+	msg, _ := testVector()
+	if err := nep413.Verify(msg, &unknown); !errors.Is(err, nep413.ErrUnsupportedKeyType) {
+		t.Fatalf("expected ErrUnsupportedKeyType, got %v", err)
+	}
+}
+
+func Test_RequireKeyType(t *testing.T) {
+	msg, res := testVector()
+	secp256k1Msg, secp256k1Res := testSecp256k1Vector()
+
+	ed25519Only := nep413.RequireKeyType(nep413.KeyTypeED25519)
+	if err := nep413.Verify(msg, res, ed25519Only); err != nil {
+		t.Fatal(err)
+	}
+	err := nep413.Verify(secp256k1Msg, secp256k1Res, ed25519Only)
+	if !errors.Is(err, nep413.ErrDisallowedKeyType) || nep413.FailureReason(err) != "disallowed_key_type" {
+		t.Fatalf("expected ErrDisallowedKeyType, got %v", err)
+	}
+
+	secp256k1Only := nep413.RequireKeyType(nep413.KeyTypeSECP256K1)
+	if err := nep413.Verify(secp256k1Msg, secp256k1Res, secp256k1Only); err != nil {
+		t.Fatal(err)
+	}
+	pub, err := res.PubKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.VerifyWithKey(msg, res, pub, secp256k1Only); !errors.Is(err, nep413.ErrDisallowedKeyType) {
+		t.Fatalf("expected ErrDisallowedKeyType, got %v", err)
+	}
+
+	// both are accepted by default, or when both are allowed
+	both := nep413.RequireKeyType(nep413.KeyTypeED25519, nep413.KeyTypeSECP256K1)
+	if err := nep413.Verify(secp256k1Msg, secp256k1Res, both); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Secp256k1SignatureMalleability(t *testing.T) {
+	msg, res := testSecp256k1Vector()
+	signature, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := func(sig []byte) *nep413.Nep413SignatureResponse {
+		encoded := *res
+		encoded.Signature = base64.StdEncoding.EncodeToString(sig)
+		return &encoded
+	}
+
+	// the flipped S value also satisfies the ECDSA equation, but is not canonical
+	var s secp256k1.ModNScalar
+	s.SetByteSlice(signature[32:64])
+	flippedS := s.Negate().Bytes()
+	highS := append(append([]byte{}, signature[:32]...), flippedS[:]...)
+	if err := nep413.Verify(msg, encode(highS)); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected a high S value to be rejected, got %v", err)
+	}
+	if err := nep413.Verify(msg, encode(append(signature[:64:64], 5))); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected an invalid recovery id to be rejected, got %v", err)
+	}
+	if err := nep413.Verify(msg, encode(append(signature[:64:64], 28))); err != nil {
+		t.Fatal(err)
+	}
+
+	// the signature store sees through the recovery id
+	store := nep413.NewMemorySignatureStore(time.Hour)
+	if err := nep413.Verify(msg, encode(signature[:64]), nep413.WithSignatureStore(store)); err != nil {
+		t.Fatal(err)
+	}
+	for _, recoveryID := range []byte{0, 1, 27} {
+		err := nep413.Verify(msg, encode(append(signature[:64:64], recoveryID)), nep413.WithSignatureStore(store))
+		if !errors.Is(err, nep413.ErrSignatureReused) {
+			t.Fatalf("recovery id %d: expected ErrSignatureReused, got %v", recoveryID, err)
+		}
+	}
+
+	if !nep413.ResponsesEqual(encode(signature[:64]), encode(append(signature[:64:64], 1))) {
+		t.Fatal("expected the signature to be equal with and without its recovery id")
+	}
+}
@@ -0,0 +1,47 @@
+package nep413
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// SignPayload signs a precomputed NEP-413 payload hash, for callers that hash the payload
+// in a separate component. It is the counterpart of VerifyHash.
+func SignPayload(priv ed25519.PrivateKey, hash [32]byte) ([]byte, error) {
+	if len(priv) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid private key length, expected %d, got %d", ed25519.PrivateKeySize, len(priv))
+	}
+
+	return ed25519.Sign(priv, hash[:]), nil
+}
+
+// Sign signs msg with priv the way an NEP-413 wallet does: it builds the tagged borsch
+// payload, hashes it with sha256, and signs the hash, returning a response with the base64
+// encoded signature and the NEAR formatted public key, which passes Verify. The response has
+// no account id; set it to the account the key belongs to. The message is left untouched.
+func Sign(msg *Nep413Message, priv ed25519.PrivateKey) (*Nep413SignatureResponse, error) {
+	res, _, err := sign(priv, msg)
+	return res, err
+}
+
+// sign signs the NEP-413 payload of msg with priv, returning the response a wallet
+// would, along with the signed hash. The response has no account id.
+func sign(priv ed25519.PrivateKey, msg *Nep413Message) (*Nep413SignatureResponse, [32]byte, error) {
+	payload, err := serializePayload(msg)
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+	hash := sha256.Sum256(payload)
+
+	signature, err := SignPayload(priv, hash)
+	if err != nil {
+		return nil, [32]byte{}, err
+	}
+
+	return &Nep413SignatureResponse{
+		Signature: base64.StdEncoding.EncodeToString(signature),
+		PublicKey: FormatPublicKey(priv.Public().(ed25519.PublicKey)),
+	}, hash, nil
+}
@@ -0,0 +1,67 @@
+package nep413_test
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_SignPayload(t *testing.T) {
+	msg, _ := testVector()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256(testPayload(t, msg))
+	sig, err := nep413.SignPayload(priv, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.VerifyHash(hash, sig, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := nep413.SignPayload(priv[:32], hash); err == nil {
+		t.Fatal("expected error for short private key")
+	}
+}
+
+func Test_Sign(t *testing.T) {
+	msg, _ := testVector()
+	msg.Tag = 0
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := nep413.Sign(msg, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Tag != 0 {
+		t.Fatal("expected the message to be left untouched")
+	}
+	if res.PublicKey != nep413.FormatPublicKey(pub) || res.AccountId != "" {
+		t.Fatalf("unexpected response %+v", res)
+	}
+	if err := nep413.VerifyWithKey(msg, res, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	keyPair := &nep413.KeyPair{PrivateKey: priv}
+	fromKeyPair, err := keyPair.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *fromKeyPair != *res {
+		t.Fatalf("expected the key pair to sign like Sign, got %+v, want %+v", fromKeyPair, res)
+	}
+
+	if _, err := nep413.Sign(msg, priv[:32]); err == nil {
+		t.Fatal("expected error for short private key")
+	}
+}
@@ -0,0 +1,111 @@
+package nep413
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSignatureReused is returned when a signature has already been accepted.
+var ErrSignatureReused = errors.New("signature has already been used")
+
+// SignatureStore records accepted signatures, to reject verbatim replays.
+// ed25519 signatures are deterministic, so a reused signature means the exact same
+// message was signed by the same key, even if the client reuses nonces on purpose.
+// Signatures are keyed by their decoded bytes, and secp256k1 signatures by their canonical
+// 64 byte r||s (high S values are rejected, and the recovery id is left out), so re-encoding a
+// signature does not bypass the store. Implementations must be safe for concurrent use.
+type SignatureStore interface {
+	// Seen atomically marks the signature as used, and reports whether it had already been used.
+	Seen(ctx context.Context, signature []byte) (bool, error)
+}
+
+// WithSignatureStore fails verification with ErrSignatureReused if the signature has
+// already been accepted. Like the nonce, the signature is only recorded once all other
+// checks have passed. Combine with VerifyStrict to also reject non-canonical encodings.
+func WithSignatureStore(store SignatureStore) VerifyOption {
+	return func(c *verifyConfig) {
+		c.signatureStore = store
+	}
+}
+
+// WithSignatureDedup fails verification with ErrSignatureReused if the same signature was
+// accepted within the last window, using a MemorySignatureStore. It is a blunt anti-replay
+// layer for apps that do not manage nonces, and is independent of WithNonceStore: a response
+// passes only if both its nonce and its signature are unused. A replay after the window is
+// accepted, so bound the age of messages with WithNonceFreshness for lasting protection.
+// It replaces any store set with WithSignatureStore. The store is created with the option,
+// so reuse the option (or a Verifier) for the window to span verifications.
+func WithSignatureDedup(window time.Duration) VerifyOption {
+	return WithSignatureStore(NewMemorySignatureStore(window))
+}
+
+// consumeSignature marks the signature as used in the signature store, if any.
+func (c *verifyConfig) consumeSignature(ctx context.Context, signature []byte) error {
+	if c.signatureStore == nil {
+		return nil
+	}
+
+	seen, err := c.signatureStore.Seen(ctx, signature)
+	if err != nil {
+		return err
+	}
+
+	if seen {
+		return ErrSignatureReused
+	}
+
+	return nil
+}
+
+// MemorySignatureStore is an in-memory SignatureStore, guarded by a mutex.
+// Signatures are forgotten after the store's ttl.
+type MemorySignatureStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	expiries  map[string]time.Time
+	lastPrune time.Time
+}
+
+var _ SignatureStore = (*MemorySignatureStore)(nil)
+
+// NewMemorySignatureStore creates an in-memory signature store that remembers signatures for ttl.
+func NewMemorySignatureStore(ttl time.Duration) *MemorySignatureStore {
+	return &MemorySignatureStore{
+		ttl:      ttl,
+		expiries: make(map[string]time.Time),
+	}
+}
+
+// Seen implements SignatureStore.
+func (m *MemorySignatureStore) Seen(_ context.Context, signature []byte) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.prune(now)
+
+	key := string(signature)
+	if expiry, ok := m.expiries[key]; ok && now.Before(expiry) {
+		return true, nil
+	}
+
+	m.expiries[key] = now.Add(m.ttl)
+	return false, nil
+}
+
+// prune removes expired signatures. It runs at most once per ttl.
+func (m *MemorySignatureStore) prune(now time.Time) {
+	if now.Sub(m.lastPrune) < m.ttl {
+		return
+	}
+	m.lastPrune = now
+
+	for key, expiry := range m.expiries {
+		if !now.Before(expiry) {
+			delete(m.expiries, key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package nep413_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_WithSignatureStore(t *testing.T) {
+	store := nep413.NewMemorySignatureStore(time.Hour)
+
+	msg, res := testVector()
+	if err := nep413.Verify(msg, res, nep413.WithSignatureStore(store)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.Verify(msg, res, nep413.WithSignatureStore(store)); !errors.Is(err, nep413.ErrSignatureReused) {
+		t.Fatalf("expected ErrSignatureReused, got %v", err)
+	}
+
+	// a non-canonical encoding of the same signature decodes to the same bytes
+	res.Signature = strings.TrimSuffix(res.Signature, "g==") + "h=="
+	if err := nep413.Verify(msg, res, nep413.WithSignatureStore(nep413.NewMemorySignatureStore(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+	if err := nep413.Verify(msg, res, nep413.WithSignatureStore(store)); !errors.Is(err, nep413.ErrSignatureReused) {
+		t.Fatalf("expected ErrSignatureReused, got %v", err)
+	}
+
+	// but is rejected in strict mode
+	if err := nep413.Verify(msg, res, nep413.VerifyStrict()); err == nil {
+		t.Fatal("expected non-canonical signature encoding to be rejected")
+	}
+}
+
+func Test_WithSignatureDedup(t *testing.T) {
+	v := nep413.NewVerifier(nep413.WithSignatureDedup(50 * time.Millisecond))
+
+	msg, res := testVector()
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrSignatureReused) {
+		t.Fatalf("expected ErrSignatureReused, got %v", err)
+	}
+
+	// the signature is accepted again once the window has passed
+	time.Sleep(60 * time.Millisecond)
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,21 @@
+package nep413
+
+import (
+	"crypto/subtle"
+	"errors"
+)
+
+// ErrStateMismatch is returned by AssertState when the response's state is not the one issued.
+var ErrStateMismatch = errors.New("response state does not match the request")
+
+// AssertState checks that the state echoed in the response is the one issued in the request,
+// to correlate a response with its request.
+// State is not covered by the signature, so it is unauthenticated: anyone relaying the
+// response can change it. It must not be relied on in place of Verify, or of a nonce check.
+func AssertState(res *Nep413SignatureResponse, expected string) error {
+	if subtle.ConstantTimeCompare([]byte(res.State), []byte(expected)) != 1 {
+		return ErrStateMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package nep413_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+)
+
+func Test_AssertState(t *testing.T) {
+	msg, expectedRes := testVector()
+
+	data, err := json.Marshal(map[string]any{
+		"signedMessage": map[string]string{
+			"accountId": "idos.near",
+			"publicKey": expectedRes.PublicKey,
+			"signature": expectedRes.Signature,
+			"state":     "session-1",
+		},
+		"nonce":     base58.Encode(msg.Nonce[:]),
+		"recipient": msg.Recipient,
+		"message":   msg.Message,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsedMsg, res, err := nep413.ParseSignedMessageEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.AssertState(res, "session-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.AssertState(res, "session-2"); !errors.Is(err, nep413.ErrStateMismatch) {
+		t.Fatalf("expected ErrStateMismatch, got %v", err)
+	}
+
+	// state is not signed, so changing it does not affect verification
+	res.State = "tampered"
+	if err := nep413.Verify(parsedMsg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	fragmentRes, err := nep413.ParseCallbackFragment("#state=session-1&signature=" + expectedRes.Signature + "&publicKey=" + expectedRes.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := nep413.AssertState(fragmentRes, "session-1"); err != nil {
+		t.Fatal(err)
+	}
+}
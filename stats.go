@@ -0,0 +1,153 @@
+package nep413
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats counts the verifications performed by a Verifier.
+// The counters are updated atomically, and are safe to read with Snapshot
+// while verifications are in progress.
+type Stats struct {
+	attempted atomic.Uint64
+	succeeded atomic.Uint64
+	failed    atomic.Uint64
+	// failedByReason maps a failure reason to its *atomic.Uint64 counter
+	failedByReason sync.Map
+	// latencyBuckets counts verifications by the first of LatencyBuckets they took at most,
+	// with a last bucket for slower ones
+	latencyBuckets [len(LatencyBuckets) + 1]atomic.Uint64
+	// latencySum is the total duration of all verifications, in nanoseconds
+	latencySum atomic.Int64
+}
+
+// LatencyBuckets are the upper bounds of the buckets verification latencies are counted in.
+// Verifications without an RPC lookup take microseconds, and those with one take about
+// a network round trip.
+var LatencyBuckets = [...]time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// StatsSnapshot is a point in time copy of a Verifier's Stats.
+type StatsSnapshot struct {
+	Attempted uint64
+	Succeeded uint64
+	Failed    uint64
+	// FailedByReason counts failures by reason, as returned by FailureReason
+	FailedByReason map[string]uint64
+	// LatencyBuckets counts verifications by latency, cumulatively: the i-th count is the
+	// number of verifications that took at most LatencyBuckets[i]. LatencyCount counts them all.
+	LatencyBuckets [len(LatencyBuckets)]uint64
+	// LatencyCount is the number of verifications in all buckets. It is read together with
+	// the buckets, so unlike Attempted, it is never less than the last of LatencyBuckets while
+	// verifications are in progress.
+	LatencyCount uint64
+	// LatencySum is the total duration of all verifications
+	LatencySum time.Duration
+}
+
+// Snapshot returns a copy of the current counters.
+func (s *Stats) Snapshot() StatsSnapshot {
+	snapshot := StatsSnapshot{
+		Attempted:      s.attempted.Load(),
+		Succeeded:      s.succeeded.Load(),
+		Failed:         s.failed.Load(),
+		FailedByReason: make(map[string]uint64),
+		LatencySum:     time.Duration(s.latencySum.Load()),
+	}
+
+	var cumulative uint64
+	for i := range snapshot.LatencyBuckets {
+		cumulative += s.latencyBuckets[i].Load()
+		snapshot.LatencyBuckets[i] = cumulative
+	}
+	snapshot.LatencyCount = cumulative + s.latencyBuckets[len(LatencyBuckets)].Load()
+
+	s.failedByReason.Range(func(key, value any) bool {
+		snapshot.FailedByReason[key.(string)] = value.(*atomic.Uint64).Load()
+		return true
+	})
+
+	return snapshot
+}
+
+// record counts the outcome of a single verification, which took elapsed.
+func (s *Stats) record(err error, elapsed time.Duration) {
+	bucket := len(LatencyBuckets)
+	for i, bound := range LatencyBuckets {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+	s.latencyBuckets[bucket].Add(1)
+	s.latencySum.Add(int64(elapsed))
+
+	s.attempted.Add(1)
+	if err == nil {
+		s.succeeded.Add(1)
+		return
+	}
+
+	s.failed.Add(1)
+	counter, _ := s.failedByReason.LoadOrStore(FailureReason(err), new(atomic.Uint64))
+	counter.(*atomic.Uint64).Add(1)
+}
+
+// failureReasons maps known errors to the reason they are counted under.
+var failureReasons = []struct {
+	err    error
+	reason string
+}{
+	{ErrInvalidSignatureEncoding, "invalid_signature_encoding"},
+	{ErrVerificationFailed, "invalid_signature"},
+	{ErrNoMatchingKey, "no_matching_key"},
+	{ErrMessageTooShort, "message_too_short"},
+	{ErrMessageTooLong, "message_too_long"},
+	{ErrMissingCallbackURL, "missing_callback_url"},
+	{ErrUnexpectedCallbackURL, "unexpected_callback_url"},
+	{ErrCallbackHostNotAllowed, "callback_host_not_allowed"},
+	{ErrCallbackRecipientMismatch, "callback_recipient_mismatch"},
+	{ErrAccountNotAllowed, "account_not_allowed"},
+	{ErrAccountNotBound, "account_not_bound"},
+	{ErrMissingAccountID, "missing_account_id"},
+	{ErrAccessKeyNotFound, "access_key_not_found"},
+	{ErrNotFullAccessKey, "not_full_access_key"},
+	{ErrRateLimited, "rate_limited"},
+	{ErrRecipientMismatch, "recipient_mismatch"},
+	{ErrEmptyRecipient, "empty_recipient"},
+	{ErrNonceExpired, "nonce_expired"},
+	{ErrNonceInFuture, "nonce_in_future"},
+	{ErrNonceNotTimestamped, "nonce_not_timestamped"},
+	{ErrNonceMismatch, "nonce_mismatch"},
+	{ErrNonceReused, "nonce_reused"},
+	{ErrSignatureReused, "signature_reused"},
+	{ErrWeakPublicKey, "weak_public_key"},
+	{ErrInvalidTag, "invalid_tag"},
+	{ErrUnsupportedKeyType, "unsupported_key_type"},
+	{ErrDisallowedKeyType, "disallowed_key_type"},
+	{ErrVerificationCancelled, "cancelled"},
+}
+
+// FailureReason returns a short, stable label for a verification error,
+// suitable for use as a metrics label. Unknown errors are labeled "other".
+func FailureReason(err error) string {
+	for _, r := range failureReasons {
+		if errors.Is(err, r.err) {
+			return r.reason
+		}
+	}
+
+	return "other"
+}
@@ -0,0 +1,85 @@
+package nep413
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// VerifyPair is a message and the response to verify against it.
+type VerifyPair struct {
+	Message  *Nep413Message
+	Response *Nep413SignatureResponse
+}
+
+// StreamResult is the outcome of verifying one VerifyPair received by VerifyStream.
+type StreamResult struct {
+	// Index is the position of the pair in the input channel, starting at 0
+	Index int
+	// Result is the verification result, or nil if Err is not
+	Result *VerifyResult
+	// Err is the verification error, if any
+	Err error
+}
+
+// VerifyStream verifies the pairs received from in concurrently, and emits each outcome
+// as soon as it is ready, so results are not in input order; use Index to correlate them.
+// The output channel is closed once in is closed and drained, or once ctx is cancelled,
+// in which case pairs still being verified may be dropped.
+// Every pair goes through VerifyContext, so the hooks and Stats apply.
+func (v *Verifier) VerifyStream(ctx context.Context, in <-chan VerifyPair) <-chan StreamResult {
+	type job struct {
+		index int
+		pair  VerifyPair
+	}
+
+	jobs := make(chan job)
+	out := make(chan StreamResult)
+
+	// the dispatcher numbers pairs in the order they are received
+	go func() {
+		defer close(jobs)
+
+		for index := 0; ; index++ {
+			select {
+			case <-ctx.Done():
+				return
+			case pair, ok := <-in:
+				if !ok {
+					return
+				}
+
+				select {
+				case jobs <- job{index, pair}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				result, err := v.VerifyContext(ctx, j.pair.Message, j.pair.Response)
+
+				select {
+				case out <- StreamResult{Index: j.index, Result: result, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,64 @@
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifyStream(t *testing.T) {
+	msg, res := testVector()
+	bad := *res
+	bad.Signature = "Zm9v"
+
+	in := make(chan nep413.VerifyPair)
+	go func() {
+		defer close(in)
+		for i := 0; i < 10; i++ {
+			pair := nep413.VerifyPair{Message: msg, Response: res}
+			if i%3 == 0 {
+				pair.Response = &bad
+			}
+			in <- pair
+		}
+	}()
+
+	v := nep413.NewVerifier()
+	seen := make(map[int]bool)
+	for result := range v.VerifyStream(context.Background(), in) {
+		if seen[result.Index] {
+			t.Fatalf("duplicate index %d", result.Index)
+		}
+		seen[result.Index] = true
+
+		if result.Index%3 == 0 {
+			if !errors.Is(result.Err, nep413.ErrVerificationFailed) || result.Result != nil {
+				t.Fatalf("%d: expected ErrVerificationFailed, got %v", result.Index, result.Err)
+			}
+		} else if result.Err != nil || result.Result == nil {
+			t.Fatalf("%d: expected success, got %v", result.Index, result.Err)
+		}
+	}
+
+	if len(seen) != 10 {
+		t.Fatalf("expected 10 results, got %d", len(seen))
+	}
+
+	if snapshot := v.Stats.Snapshot(); snapshot.Attempted != 10 {
+		t.Fatalf("expected 10 attempts, got %d", snapshot.Attempted)
+	}
+}
+
+func Test_VerifyStreamCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the input is never closed, so only cancellation closes the output
+	in := make(chan nep413.VerifyPair)
+	out := nep413.NewVerifier().VerifyStream(ctx, in)
+	cancel()
+
+	for range out {
+	}
+}
@@ -0,0 +1,45 @@
+package nep413
+
+// MessageTemplate holds the fixed fields of a message that is requested repeatedly,
+// e.g. a login message. It is immutable, and safe for concurrent use.
+type MessageTemplate struct {
+	message     string
+	recipient   string
+	callbackUrl *string
+}
+
+// NewMessageTemplate returns a template for messages with the given fields.
+// callbackUrl is optional, and is copied.
+func NewMessageTemplate(message, recipient string, callbackUrl *string) *MessageTemplate {
+	return &MessageTemplate{
+		message:     message,
+		recipient:   recipient,
+		callbackUrl: copyString(callbackUrl),
+	}
+}
+
+// Instantiate returns a new message from the template, with a fresh random nonce.
+// The message shares no state with the template or other instances, so it may be modified freely.
+func (t *MessageTemplate) Instantiate() (*Nep413Message, error) {
+	nonce, err := NewNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Nep413Message{
+		Message:     t.message,
+		Nonce:       nonce,
+		Recipient:   t.recipient,
+		CallbackUrl: copyString(t.callbackUrl),
+	}, nil
+}
+
+// copyString returns a copy of s, or nil if s is nil.
+func copyString(s *string) *string {
+	if s == nil {
+		return nil
+	}
+
+	c := *s
+	return &c
+}
@@ -0,0 +1,53 @@
+package nep413_test
+
+import (
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_MessageTemplate(t *testing.T) {
+	callback := "https://idos.network/callback"
+	template := nep413.NewMessageTemplate("idOS authentication", "idos.network", &callback)
+
+	// changing the caller's callback does not affect the template
+	callback = "https://attacker.example"
+
+	first, err := template.Instantiate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := template.Instantiate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.Nonce == second.Nonce {
+		t.Fatal("expected a fresh nonce per message")
+	}
+
+	if *first.CallbackUrl != "https://idos.network/callback" {
+		t.Fatalf("unexpected callback url %s", *first.CallbackUrl)
+	}
+
+	// messages are independent of each other
+	*first.CallbackUrl = "https://changed.example"
+	first.Message = "changed"
+	if *second.CallbackUrl != "https://idos.network/callback" || second.Message != "idOS authentication" {
+		t.Fatal("expected instances to be independent")
+	}
+
+	// verifying an instance does not affect the template
+	res, _ := testSign(t, second)
+	if err := nep413.Verify(second, res); err != nil {
+		t.Fatal(err)
+	}
+
+	third, err := template.Instantiate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Tag != 0 || third.Message != "idOS authentication" || *third.CallbackUrl != "https://idos.network/callback" {
+		t.Fatal("expected the template to be unchanged")
+	}
+}
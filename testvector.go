@@ -0,0 +1,63 @@
+package nep413
+
+import (
+	"crypto/ed25519"
+	"fmt"
+)
+
+// TestVector is a complete set of values for an NEP-413 signature, for checking other
+// implementations against this one.
+type TestVector struct {
+	// Message is the signed message, with the tag set
+	Message *Nep413Message `json:"message"`
+	// Payload is the borsch serialization of the message
+	Payload []byte `json:"payload"`
+	// PayloadHash is the sha256 hash of the payload, which is what gets signed
+	PayloadHash [32]byte `json:"payloadHash"`
+	// Response holds the signature and the NEAR formatted public key
+	Response *Nep413SignatureResponse `json:"response"`
+}
+
+// GenerateTestVector signs msg with the ed25519 key derived from the 32 byte seed.
+// ed25519 signatures are deterministic, so the output only depends on the seed and message.
+// The seed is a private key, and must never be one that is used for real accounts.
+func GenerateTestVector(seed []byte, msg *Nep413Message) (*TestVector, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid seed length, expected %d, got %d", ed25519.SeedSize, len(seed))
+	}
+
+	// copy the message, so that the caller's is left untouched
+	signed := *msg
+	signed.Tag = Nep413Tag
+	res, hash, err := sign(ed25519.NewKeyFromSeed(seed), &signed)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := serializePayload(&signed)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TestVector{
+		Message:     &signed,
+		Payload:     payload,
+		PayloadHash: hash,
+		Response:    res,
+	}, nil
+}
+
+// SignForTest signs msg with the ed25519 key derived from seed, returning a response that
+// passes Verify, for tests of packages built on this one. Like GenerateTestVector, the output
+// only depends on the seed and message, and the message is left untouched. It panics if the
+// message cannot be serialized.
+// It is for tests only: the seed is a private key, and must never be one that is used for
+// real accounts.
+func SignForTest(seed [32]byte, msg *Nep413Message) *Nep413SignatureResponse {
+	res, _, err := sign(ed25519.NewKeyFromSeed(seed[:]), msg)
+	if err != nil {
+		panic(fmt.Sprintf("nep413: SignForTest: %v", err))
+	}
+
+	return res
+}
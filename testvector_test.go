@@ -0,0 +1,60 @@
+package nep413_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_GenerateTestVector(t *testing.T) {
+	msg, _ := testVector()
+	seed := bytes.Repeat([]byte{7}, 32)
+
+	vector, err := nep413.GenerateTestVector(seed, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := nep413.GenerateTestVector(seed, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *vector.Response != *again.Response || !bytes.Equal(vector.Payload, again.Payload) {
+		t.Fatal("expected test vectors to be deterministic")
+	}
+
+	if vector.PayloadHash != sha256.Sum256(vector.Payload) || !bytes.Equal(vector.Payload, testPayload(t, msg)) {
+		t.Fatal("unexpected payload")
+	}
+
+	if err := nep413.Verify(vector.Message, vector.Response); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := nep413.GenerateTestVector([]byte{1}, msg); err == nil {
+		t.Fatal("expected error for short seed")
+	}
+}
+
+func Test_SignForTest(t *testing.T) {
+	msg, _ := testVector()
+	seed := [32]byte{7}
+
+	res := nep413.SignForTest(seed, msg)
+	if msg.Tag != 0 {
+		t.Fatal("expected the message to be left untouched")
+	}
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	if again := nep413.SignForTest(seed, msg); *again != *res {
+		t.Fatal("expected the same response for the same seed and message")
+	}
+	if other := nep413.SignForTest([32]byte{8}, msg); other.PublicKey == res.PublicKey {
+		t.Fatal("expected a different key for a different seed")
+	}
+}
@@ -0,0 +1,47 @@
+package nep413
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// ErrThresholdNotMet is returned by VerifyThreshold when fewer than threshold of the
+// expected keys signed the message.
+var ErrThresholdNotMet = errors.New("not enough distinct signers")
+
+// VerifyThreshold verifies that at least threshold distinct keys out of keys signed msg,
+// e.g. to authorize an action on an account shared by several signers. Each response is
+// verified against keys, ignoring the public key it claims, like VerifyWithCandidateKeys.
+// Responses that do not verify are not counted, and a key that signed several responses is
+// counted once. The keys that signed are returned, in the order of responses, along with
+// ErrThresholdNotMet if there are fewer than threshold of them.
+func VerifyThreshold(msg *Nep413Message, responses []*Nep413SignatureResponse, keys []ed25519.PublicKey, threshold int) ([]ed25519.PublicKey, error) {
+	if threshold < 1 || threshold > len(keys) {
+		return nil, fmt.Errorf("invalid threshold %d for %d keys", threshold, len(keys))
+	}
+
+	cfg := newVerifyConfig(nil)
+	signed := make(map[string]struct{}, len(responses))
+	var signers []ed25519.PublicKey
+
+	for _, res := range responses {
+		result, err := verify(context.Background(), cfg, msg, res, keys)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := signed[string(result.PublicKey)]; ok {
+			continue
+		}
+		signed[string(result.PublicKey)] = struct{}{}
+		signers = append(signers, result.PublicKey)
+	}
+
+	if len(signers) < threshold {
+		return signers, fmt.Errorf("%w: %d of %d required", ErrThresholdNotMet, len(signers), threshold)
+	}
+
+	return signers, nil
+}
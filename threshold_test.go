@@ -0,0 +1,48 @@
+package nep413_test
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifyThreshold(t *testing.T) {
+	msg, _ := testVector()
+
+	var responses []*nep413.Nep413SignatureResponse
+	var keys []ed25519.PublicKey
+	for i := 0; i < 3; i++ {
+		res, priv := testSign(t, msg)
+		responses = append(responses, res)
+		keys = append(keys, priv.Public().(ed25519.PublicKey))
+	}
+
+	// a signer outside of the set is not counted
+	outsider, _ := testSign(t, msg)
+
+	signers, err := nep413.VerifyThreshold(msg, []*nep413.Nep413SignatureResponse{responses[0], outsider, responses[2]}, keys, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signers) != 2 || !signers[0].Equal(keys[0]) || !signers[1].Equal(keys[2]) {
+		t.Fatalf("unexpected signers %v", signers)
+	}
+
+	// the same signer is counted once
+	signers, err = nep413.VerifyThreshold(msg, []*nep413.Nep413SignatureResponse{responses[0], responses[0], outsider}, keys, 2)
+	if !errors.Is(err, nep413.ErrThresholdNotMet) {
+		t.Fatalf("expected ErrThresholdNotMet, got %v", err)
+	}
+	if len(signers) != 1 {
+		t.Fatalf("expected 1 signer, got %d", len(signers))
+	}
+
+	if _, err := nep413.VerifyThreshold(msg, responses, keys, 4); err == nil {
+		t.Fatal("expected error for a threshold above the number of keys")
+	}
+	if _, err := nep413.VerifyThreshold(msg, responses, keys, 0); err == nil {
+		t.Fatal("expected error for a zero threshold")
+	}
+}
@@ -0,0 +1,84 @@
+package nep413
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mr-tron/base58"
+)
+
+// EncodeAuthToken encodes a signed message and its response as a single url-safe string,
+// suitable for sending in a header. The token is the unpadded base64url encoding of the
+// wallet-selector envelope parsed by ParseSignedMessageEnvelope.
+func EncodeAuthToken(msg *Nep413Message, res *Nep413SignatureResponse) (string, error) {
+	nonce, err := json.Marshal(base58.Encode(msg.Nonce[:]))
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(signedMessageEnvelope{
+		SignedMessage: res,
+		Nonce:         nonce,
+		Recipient:     msg.Recipient,
+		Message:       msg.Message,
+		CallbackUrl:   msg.CallbackUrl,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ParseAuthToken decodes a token created by EncodeAuthToken.
+func ParseAuthToken(token string) (*Nep413Message, *Nep413SignatureResponse, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid auth token encoding: %w", err)
+	}
+
+	return ParseSignedMessageEnvelope(data)
+}
+
+// bearerTokenPrefix distinguishes NEP-413 bearer tokens from other bearer tokens.
+const bearerTokenPrefix = "nep413."
+
+// ErrMalformedBearer is returned by VerifyBearer when the header is not an NEP-413 bearer token.
+var ErrMalformedBearer = errors.New("malformed NEP-413 bearer token")
+
+// EncodeBearerToken encodes a signed message and its response as a bearer token of the form
+// "nep413.<auth token>", to be sent as "Authorization: Bearer nep413.<auth token>".
+func EncodeBearerToken(msg *Nep413Message, res *Nep413SignatureResponse) (string, error) {
+	token, err := EncodeAuthToken(msg, res)
+	if err != nil {
+		return "", err
+	}
+
+	return bearerTokenPrefix + token, nil
+}
+
+// VerifyBearer verifies an Authorization header of the form "Bearer nep413.<auth token>" with
+// the verifier's full policy. A header that cannot be decoded fails with an error wrapping
+// ErrMalformedBearer, so it can be told apart from a failed verification.
+func VerifyBearer(ctx context.Context, v *Verifier, header string) (*VerifyResult, error) {
+	scheme, credentials, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return nil, fmt.Errorf("%w: expected Bearer scheme", ErrMalformedBearer)
+	}
+
+	token, ok := strings.CutPrefix(strings.TrimSpace(credentials), bearerTokenPrefix)
+	if !ok {
+		return nil, fmt.Errorf("%w: expected %s prefix", ErrMalformedBearer, bearerTokenPrefix)
+	}
+
+	msg, res, err := ParseAuthToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedBearer, err)
+	}
+
+	return v.VerifyContext(ctx, msg, res)
+}
@@ -0,0 +1,46 @@
+package nep413_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifyBearer(t *testing.T) {
+	ctx := context.Background()
+	v := nep413.NewVerifier()
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	token, err := nep413.EncodeBearerToken(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := nep413.VerifyBearer(ctx, v, "Bearer "+token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccountId != "idos.near" {
+		t.Fatalf("unexpected account id %s", result.AccountId)
+	}
+
+	for _, header := range []string{"", "Basic abc", "Bearer abc", "Bearer nep413.!!!"} {
+		if _, err := nep413.VerifyBearer(ctx, v, header); !errors.Is(err, nep413.ErrMalformedBearer) {
+			t.Fatalf("expected ErrMalformedBearer for %q, got %v", header, err)
+		}
+	}
+
+	msg.Message = "tampered"
+	token, err = nep413.EncodeBearerToken(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = nep413.VerifyBearer(ctx, v, "Bearer "+token)
+	if !errors.Is(err, nep413.ErrVerificationFailed) || errors.Is(err, nep413.ErrMalformedBearer) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+}
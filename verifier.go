@@ -0,0 +1,174 @@
+package nep413
+
+import (
+	"context"
+	"crypto/ed25519"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// VerifyResult describes a successfully verified signature.
+// It is the single object to pass downstream after authentication.
+type VerifyResult struct {
+	// AccountId is the account id claimed by the response
+	AccountId string
+	// PublicKey is the key that validated the signature, if it is an ed25519 key
+	PublicKey ed25519.PublicKey
+	// Secp256k1PublicKey is the key that validated the signature, if it is a secp256k1 key
+	Secp256k1PublicKey *secp256k1.PublicKey
+	// KeyType is the type of the key that validated the signature
+	KeyType KeyType
+	// NearKey is the key in NEAR's format, e.g. "ed25519:8HnzkUaX21h99idPghFajoV3JZvy3SmJ4mqVwSVfLByg"
+	NearKey string
+	// Nonce is the signed nonce
+	Nonce [32]byte
+	// Recipient is the signed recipient
+	Recipient string
+	// PayloadHash is the sha256 hash of the signed borsch payload. It is the exact
+	// digest whose signature was checked, so it can be stored as a tamper-evident audit record.
+	// (With WithPayloadHashing(HashNone), the raw payload is verified instead.)
+	PayloadHash [32]byte
+	// MatchedRecipient is the expected recipient that the signed recipient matched,
+	// or empty if no recipient was expected
+	MatchedRecipient string
+	// AccessKey is the on-chain access key, including its nonce and the block height it was
+	// read at, if the access key check is enabled (see WithAccessKeyCheck)
+	AccessKey *AccessKeyInfo
+	// RecipientTrimmed is set if the message was signed with its recipient trimmed of
+	// whitespace, in which case Recipient is the trimmed recipient (see TryTrimmedRecipient)
+	RecipientTrimmed bool
+	// VerifiedAt is when the signature was verified, according to the verifier's clock
+	VerifiedAt time.Time
+	// AccountIdDerived is set if AccountId is the signing key's implicit account, rather than
+	// claimed by the response (see DeriveImplicitAccountID)
+	AccountIdDerived bool
+}
+
+// Verifier verifies NEP-413 signatures against a fixed set of options.
+// It is safe for concurrent use, as long as the hooks and NowFunc are not modified
+// once verification has started. The zero value verifies without options, like NewVerifier().
+type Verifier struct {
+	// OnBeforeVerify, if set, is called before any checks are run.
+	OnBeforeVerify func(ctx context.Context, msg *Nep413Message, res *Nep413SignatureResponse)
+	// OnAfterVerify, if set, is called with the outcome of every verification,
+	// after OnBeforeVerify. result is nil if err is not.
+	OnAfterVerify func(ctx context.Context, result *VerifyResult, err error)
+
+	// NowFunc, if set, is the clock used instead of time.Now. All time-based checks, i.e.
+	// nonce freshness and the rate limit, compare against it, so it can be a fixed clock in
+	// tests, or a synchronized network time source. The access key cache expires keys by it
+	// too, while stores keep their own clocks.
+	NowFunc func() time.Time
+
+	// Stats counts the verifications performed by the verifier.
+	Stats Stats
+
+	cfg *verifyConfig
+}
+
+// NewVerifier creates a Verifier that applies the given options to every verification.
+func NewVerifier(opts ...VerifyOption) *Verifier {
+	return &Verifier{
+		cfg: newVerifyConfig(opts),
+	}
+}
+
+// Verify verifies an NEP-413 signature using the verifier's options.
+func (v *Verifier) Verify(msg *Nep413Message, res *Nep413SignatureResponse) (*VerifyResult, error) {
+	return v.VerifyContext(context.Background(), msg, res)
+}
+
+// VerifyContext verifies an NEP-413 signature, applying the verifier's full policy.
+// It is the production entry point, and is safe for concurrent use.
+//
+// Policies are evaluated in this order, stopping at the first failure:
+//  1. OnBeforeVerify is called
+//  2. the public key is parsed from the response, after checking its type (RequireKeyType):
+//     ErrUnsupportedKeyType, ErrDisallowedKeyType
+//  3. recipient binding (RejectEmptyRecipient, WithExpectedRecipients): ErrEmptyRecipient, ErrRecipientMismatch
+//  4. message length (WithMessageLengthRange): ErrMessageTooShort, ErrMessageTooLong
+//  5. the nonce (WithExpectedNonce, WithRequestIDNonce, WithNonceFreshness):
+//     ErrNonceMismatch, ErrNonceNotTimestamped, ErrNonceExpired, ErrNonceInFuture
+//  6. callback url policy (RequireCallbackURL, ForbidCallbackURL, WithAllowedCallbackHosts,
+//     RequireCallbackMatchesRecipient): ErrMissingCallbackURL, ErrUnexpectedCallbackURL,
+//     ErrCallbackHostNotAllowed, ErrCallbackRecipientMismatch
+//  7. the signature: ErrVerificationFailed, and ErrWeakPublicKey in strict mode
+//  8. account allowlist (WithAllowedAccounts): ErrAccountNotAllowed
+//  9. on-chain access key (WithAccessKeyCheck, RequireFullAccessKey): ErrMissingAccountID,
+//     ErrAccessKeyNotFound, ErrNotFullAccessKey, or an RPC error
+//  10. the rate limit, charged to the signer (WithRateLimit): ErrRateLimited
+//  11. replay protection (WithNonceStore, WithSignatureStore): ErrNonceReused, ErrSignatureReused
+//  12. OnAfterVerify is called with the outcome, which is also counted in Stats
+//
+// The context is checked before verification starts and before any store is written to,
+// so a cancelled verification never consumes a nonce; it fails with ErrVerificationCancelled,
+// also when the access key lookup is cut short. Stores receive the context.
+// The hooks run synchronously in the verification path, so slow hooks
+// slow down verification.
+func (v *Verifier) VerifyContext(ctx context.Context, msg *Nep413Message, res *Nep413SignatureResponse) (*VerifyResult, error) {
+	return v.verify(ctx, v.config(), msg, res)
+}
+
+// verify runs the hooks and counts the Stats around verifying against cfg.
+func (v *Verifier) verify(ctx context.Context, cfg *verifyConfig, msg *Nep413Message, res *Nep413SignatureResponse) (*VerifyResult, error) {
+	if v.OnBeforeVerify != nil {
+		v.OnBeforeVerify(ctx, msg, res)
+	}
+
+	start := time.Now()
+	result, err := verifyResponseKey(ctx, cfg, msg, res)
+	v.Stats.record(err, time.Since(start))
+
+	if v.OnAfterVerify != nil {
+		v.OnAfterVerify(ctx, result, err)
+	}
+
+	return result, err
+}
+
+// Close releases the verifier's resources, for an orderly shutdown: it flushes the access key
+// cache, and closes the idle connections of the access key viewer, if it has any (as an
+// *RPCClient does). Stores and viewers passed as options are owned by the caller, so they
+// are not closed; the verifier runs no background goroutines of its own to stop.
+// It is safe to call multiple times, and always returns nil. The verifier can still be used
+// afterwards, but starts over with an empty cache.
+func (v *Verifier) Close() error {
+	cfg := v.policy()
+	viewer := cfg.accessKeys
+	if cfg.accessKeyCache != nil {
+		cfg.accessKeyCache.flush()
+		viewer = cfg.accessKeyCache.viewer
+	}
+
+	if closer, ok := viewer.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+
+	return nil
+}
+
+// defaultVerifyConfig is the policy of a zero value Verifier.
+var defaultVerifyConfig = newVerifyConfig(nil)
+
+// policy returns the verifier's options, or the defaults if it was not created by NewVerifier.
+func (v *Verifier) policy() *verifyConfig {
+	if v.cfg == nil {
+		return defaultVerifyConfig
+	}
+
+	return v.cfg
+}
+
+// config returns the verifier's policy, using its clock.
+func (v *Verifier) config() *verifyConfig {
+	cfg := v.policy()
+	if v.NowFunc == nil {
+		return cfg
+	}
+
+	// the policy is shared by concurrent verifications, so the clock is set on a copy
+	withClock := *cfg
+	withClock.nowFunc = v.NowFunc
+	return &withClock
+}
@@ -0,0 +1,279 @@
+package nep413
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// VerifierConfig is the declarative policy of a Verifier, e.g. as read from a config file.
+// It encodes to JSON, with durations as strings such as "5m". Each field corresponds to the
+// option of the same name, and its zero value to the option not being given; for lists, only
+// null means that, while an empty list allows nothing. Resources such as
+// stores and the RPC client are not part of it; they are supplied as Dependencies.
+// Per-request policy, i.e. WithExpectedNonce and WithRequestIDNonce, is not part of it either.
+type VerifierConfig struct {
+	// ExpectedRecipients are the recipients the message may be signed for (see WithExpectedRecipients)
+	ExpectedRecipients []string `json:"expectedRecipients"`
+	// RejectEmptyRecipient rejects an empty signed recipient (see RejectEmptyRecipient)
+	RejectEmptyRecipient bool `json:"rejectEmptyRecipient,omitempty"`
+	// TryTrimmedRecipient retries with a trimmed recipient (see TryTrimmedRecipient)
+	TryTrimmedRecipient bool `json:"tryTrimmedRecipient,omitempty"`
+	// AllowedAccounts are the patterns of allowed accounts (see CompileAccountMatcher)
+	AllowedAccounts []string `json:"allowedAccounts"`
+	// DeriveImplicitAccountID defaults the account id to the implicit account (see DeriveImplicitAccountID)
+	DeriveImplicitAccountID bool `json:"deriveImplicitAccountId,omitempty"`
+	// RateLimit limits attempts per account (see WithRateLimit)
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+	// MinMessageLength and MaxMessageLength bound the message length (see WithMessageLengthRange)
+	MinMessageLength int `json:"minMessageLength,omitempty"`
+	MaxMessageLength int `json:"maxMessageLength,omitempty"`
+	// AllowEmptyMessage exempts empty messages from MinMessageLength (see AllowEmptyMessage)
+	AllowEmptyMessage bool `json:"allowEmptyMessage,omitempty"`
+	// MessageNormalization is "NFC", "NFD", "NFKC" or "NFKD" (see WithMessageNormalization)
+	MessageNormalization string `json:"messageNormalization,omitempty"`
+	// NonceMaxAge bounds the age of timestamped nonces (see WithNonceFreshness)
+	NonceMaxAge *Duration `json:"nonceMaxAge,omitempty"`
+	// NonceFutureSkew bounds how far in the future nonces may be timestamped (see RejectFutureNonce);
+	// it is the smaller of the skews of WithNonceFreshness and RejectFutureNonce
+	NonceFutureSkew *Duration `json:"nonceFutureSkew,omitempty"`
+	// RequireCallbackURL requires a callback url (see RequireCallbackURL)
+	RequireCallbackURL bool `json:"requireCallbackUrl,omitempty"`
+	// ForbidCallbackURL forbids a callback url (see ForbidCallbackURL)
+	ForbidCallbackURL bool `json:"forbidCallbackUrl,omitempty"`
+	// AllowedCallbackHosts are the allowed callback url hosts (see WithAllowedCallbackHosts)
+	AllowedCallbackHosts []string `json:"allowedCallbackHosts"`
+	// CallbackMatchesRecipient binds the callback url to the recipient (see RequireCallbackMatchesRecipient)
+	CallbackMatchesRecipient bool `json:"callbackMatchesRecipient,omitempty"`
+	// PresetTag uses the message's tag as is (see WithPresetTag)
+	PresetTag bool `json:"presetTag,omitempty"`
+	// Tag is the payload tag, if not Nep413Tag (see WithTag)
+	Tag *uint32 `json:"tag,omitempty"`
+	// PayloadHashing is "sha256" (the default) or "none" (see WithPayloadHashing)
+	PayloadHashing string `json:"payloadHashing,omitempty"`
+	// StrictEncoding requires canonically encoded signatures (see VerifyStrict)
+	StrictEncoding bool `json:"strictEncoding,omitempty"`
+	// RejectWeakKeys rejects small-order public keys (see VerifyStrict)
+	RejectWeakKeys bool `json:"rejectWeakKeys,omitempty"`
+	// AllowedKeyTypes are the allowed key types, e.g. "ed25519" (see RequireKeyType)
+	AllowedKeyTypes []string `json:"allowedKeyTypes"`
+	// RequireFullAccessKey rejects function call keys (see RequireFullAccessKey)
+	RequireFullAccessKey bool `json:"requireFullAccessKey,omitempty"`
+	// AccessKeyCacheTTL caches looked up access keys (see WithAccessKeyCache)
+	AccessKeyCacheTTL Duration `json:"accessKeyCacheTtl,omitempty"`
+	// NegativeCacheTTL caches unknown access keys (see WithNegativeCacheTTL)
+	NegativeCacheTTL Duration `json:"negativeCacheTtl,omitempty"`
+	// AccessKeyCacheSize bounds the access key cache (see WithAccessKeyCacheSize)
+	AccessKeyCacheSize int `json:"accessKeyCacheSize,omitempty"`
+}
+
+// RateLimitConfig is the configuration of WithRateLimit.
+type RateLimitConfig struct {
+	PerWindow int      `json:"perWindow"`
+	Window    Duration `json:"window"`
+}
+
+// Dependencies are the resources a Verifier built by NewVerifierFromConfig is wired to.
+// All of them are optional.
+type Dependencies struct {
+	// NonceStore records consumed nonces (see WithNonceStore)
+	NonceStore NonceStore
+	// SignatureStore records accepted signatures (see WithSignatureStore)
+	SignatureStore SignatureStore
+	// AccessKeys looks up access keys, e.g. an *RPCClient (see WithAccessKeyCheck)
+	AccessKeys AccessKeyViewer
+	// NowFunc is the verifier's clock (see Verifier.NowFunc)
+	NowFunc func() time.Time
+	// OnBeforeVerify and OnAfterVerify are the verifier's hooks, e.g. for logging
+	// (see Verifier.OnBeforeVerify and Verifier.OnAfterVerify)
+	OnBeforeVerify func(ctx context.Context, msg *Nep413Message, res *Nep413SignatureResponse)
+	OnAfterVerify  func(ctx context.Context, result *VerifyResult, err error)
+}
+
+// Duration is a time.Duration that is encoded as a string, e.g. "1m30s", in JSON.
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing the duration with time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+// normalizationForms are the names of the Unicode normalization forms in a VerifierConfig.
+var normalizationForms = map[string]norm.Form{
+	"NFC":  norm.NFC,
+	"NFD":  norm.NFD,
+	"NFKC": norm.NFKC,
+	"NFKD": norm.NFKD,
+}
+
+// hashModes are the names of the payload hashing modes in a VerifierConfig.
+var hashModes = map[string]HashMode{
+	"sha256": HashSHA256,
+	"none":   HashNone,
+}
+
+// Config returns the verifier's policy. NewVerifierFromConfig reconstructs a verifier with
+// the same policy from it, given the same dependencies. The state of the rate limiter and
+// the access key cache is not part of it.
+func (v *Verifier) Config() VerifierConfig {
+	c := v.policy()
+	cfg := VerifierConfig{
+		ExpectedRecipients:       c.expectedRecipients,
+		RejectEmptyRecipient:     c.rejectEmptyRecipient,
+		TryTrimmedRecipient:      c.tryTrimmedRecipient,
+		DeriveImplicitAccountID:  c.deriveImplicitAccount,
+		MinMessageLength:         c.minMessageLength,
+		MaxMessageLength:         c.maxMessageLength,
+		AllowEmptyMessage:        c.allowEmptyMessage,
+		RequireCallbackURL:       c.requireCallbackURL,
+		ForbidCallbackURL:        c.forbidCallbackURL,
+		AllowedCallbackHosts:     c.allowedCallbackHosts,
+		CallbackMatchesRecipient: c.callbackMatchesRecipient,
+		PresetTag:                c.presetTag,
+		StrictEncoding:           c.strictEncoding,
+		RejectWeakKeys:           c.rejectWeakKeys,
+		RequireFullAccessKey:     c.requireFullAccess,
+		AccessKeyCacheTTL:        Duration(c.accessKeyCacheTTL),
+		NegativeCacheTTL:         Duration(c.negativeCacheTTL),
+		AccessKeyCacheSize:       c.accessKeyCacheSize,
+	}
+
+	if c.allowedAccounts != nil {
+		// an empty allowlist allows no account, unlike none
+		cfg.AllowedAccounts = append([]string{}, c.allowedAccounts.patterns...)
+	}
+	if c.limiter != nil {
+		cfg.RateLimit = &RateLimitConfig{PerWindow: int(c.limiter.perWindow), Window: Duration(c.limiter.window)}
+	}
+	if c.messageNormalization != nil {
+		for name, form := range normalizationForms {
+			if form == *c.messageNormalization {
+				cfg.MessageNormalization = name
+			}
+		}
+	}
+	if c.nonceMaxAge != nil {
+		maxAge := Duration(*c.nonceMaxAge)
+		cfg.NonceMaxAge = &maxAge
+	}
+	if skew := c.futureSkew(); skew != nil {
+		futureSkew := Duration(*skew)
+		cfg.NonceFutureSkew = &futureSkew
+	}
+	if c.tag != nil {
+		tag := *c.tag
+		cfg.Tag = &tag
+	}
+	if c.hashMode == HashNone {
+		cfg.PayloadHashing = "none"
+	}
+	for _, keyType := range c.allowedKeyTypes {
+		cfg.AllowedKeyTypes = append(cfg.AllowedKeyTypes, keyType.String())
+	}
+
+	return cfg
+}
+
+// NewVerifierFromConfig creates a Verifier with the policy in cfg, wired to deps. It fails
+// if cfg is invalid, e.g. because of an invalid account pattern or an unknown key type.
+func NewVerifierFromConfig(cfg VerifierConfig, deps Dependencies) (*Verifier, error) {
+	opts := []VerifyOption{
+		WithMessageLengthRange(cfg.MinMessageLength, cfg.MaxMessageLength),
+		func(c *verifyConfig) {
+			c.expectedRecipients = cfg.ExpectedRecipients
+			c.rejectEmptyRecipient = cfg.RejectEmptyRecipient
+			c.tryTrimmedRecipient = cfg.TryTrimmedRecipient
+			c.allowEmptyMessage = cfg.AllowEmptyMessage
+			c.deriveImplicitAccount = cfg.DeriveImplicitAccountID
+			c.requireCallbackURL = cfg.RequireCallbackURL
+			c.forbidCallbackURL = cfg.ForbidCallbackURL
+			c.callbackMatchesRecipient = cfg.CallbackMatchesRecipient
+			c.presetTag = cfg.PresetTag
+			c.strictEncoding = cfg.StrictEncoding
+			c.rejectWeakKeys = cfg.RejectWeakKeys
+			c.requireFullAccess = cfg.RequireFullAccessKey
+		},
+		WithAccessKeyCache(time.Duration(cfg.AccessKeyCacheTTL)),
+		WithNegativeCacheTTL(time.Duration(cfg.NegativeCacheTTL)),
+		WithAccessKeyCacheSize(cfg.AccessKeyCacheSize),
+	}
+
+	if cfg.AllowedAccounts != nil {
+		matcher, err := CompileAccountMatcher(cfg.AllowedAccounts)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithAccountMatcher(matcher))
+	}
+	if cfg.RateLimit != nil {
+		opts = append(opts, WithRateLimit(cfg.RateLimit.PerWindow, time.Duration(cfg.RateLimit.Window)))
+	}
+	if cfg.MessageNormalization != "" {
+		form, ok := normalizationForms[cfg.MessageNormalization]
+		if !ok {
+			return nil, fmt.Errorf("unknown message normalization %q", cfg.MessageNormalization)
+		}
+		opts = append(opts, WithMessageNormalization(form))
+	}
+	if cfg.NonceMaxAge != nil {
+		maxAge := time.Duration(*cfg.NonceMaxAge)
+		opts = append(opts, func(c *verifyConfig) { c.nonceMaxAge = &maxAge })
+	}
+	if cfg.NonceFutureSkew != nil {
+		opts = append(opts, RejectFutureNonce(time.Duration(*cfg.NonceFutureSkew)))
+	}
+	if cfg.Tag != nil {
+		opts = append(opts, WithTag(*cfg.Tag))
+	}
+	if cfg.AllowedCallbackHosts != nil {
+		opts = append(opts, WithAllowedCallbackHosts(cfg.AllowedCallbackHosts...))
+	}
+	if cfg.PayloadHashing != "" {
+		mode, ok := hashModes[cfg.PayloadHashing]
+		if !ok {
+			return nil, fmt.Errorf("unknown payload hashing %q", cfg.PayloadHashing)
+		}
+		opts = append(opts, WithPayloadHashing(mode))
+	}
+	if cfg.AllowedKeyTypes != nil {
+		keyTypes := make([]KeyType, len(cfg.AllowedKeyTypes))
+		for i, name := range cfg.AllowedKeyTypes {
+			keyType, err := KeyTypeOf(name + ":")
+			if err != nil {
+				return nil, err
+			}
+			keyTypes[i] = keyType
+		}
+		opts = append(opts, RequireKeyType(keyTypes...))
+	}
+
+	if deps.NonceStore != nil {
+		opts = append(opts, WithNonceStore(deps.NonceStore))
+	}
+	if deps.SignatureStore != nil {
+		opts = append(opts, WithSignatureStore(deps.SignatureStore))
+	}
+	if deps.AccessKeys != nil {
+		opts = append(opts, WithAccessKeyCheck(deps.AccessKeys))
+	}
+
+	v := NewVerifier(opts...)
+	v.NowFunc = deps.NowFunc
+	v.OnBeforeVerify = deps.OnBeforeVerify
+	v.OnAfterVerify = deps.OnAfterVerify
+
+	return v, nil
+}
@@ -0,0 +1,102 @@
+package nep413_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+	"golang.org/x/text/unicode/norm"
+)
+
+func Test_VerifierConfig(t *testing.T) {
+	v := nep413.NewVerifier(
+		nep413.WithExpectedRecipients("idos.network", "app.idos.network"),
+		nep413.VerifyStrict(),
+		nep413.WithNonceFreshness(5*time.Minute, 30*time.Second),
+		nep413.WithRateLimit(10, time.Minute),
+		nep413.WithMessageLengthRange(1, 1024),
+		nep413.WithMessageNormalization(norm.NFC),
+		nep413.WithAllowedCallbackHosts("*.idos.network"),
+		nep413.RequireKeyType(nep413.KeyTypeED25519),
+		nep413.WithAccessKeyCache(time.Hour),
+	)
+
+	cfg := v.Config()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"nonceMaxAge":"5m0s"`) {
+		t.Fatalf("expected durations to be encoded as strings, got %s", data)
+	}
+
+	var decoded nep413.VerifierConfig
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	var afterVerify int
+	rebuilt, err := nep413.NewVerifierFromConfig(decoded, nep413.Dependencies{
+		NonceStore: nep413.NewMemoryNonceStore(time.Hour),
+		OnAfterVerify: func(ctx context.Context, result *nep413.VerifyResult, err error) {
+			afterVerify++
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rebuilt.Config(), cfg) {
+		t.Fatalf("expected the same config, got %+v, want %+v", rebuilt.Config(), cfg)
+	}
+
+	// the policy is applied, and the dependencies are wired
+	msg, res := testVector()
+	if _, err := rebuilt.Verify(msg, res); !errors.Is(err, nep413.ErrNonceNotTimestamped) || afterVerify != 1 {
+		t.Fatalf("expected ErrNonceNotTimestamped, got %v", err)
+	}
+	msg.Recipient = "other.network"
+	if _, err := rebuilt.Verify(msg, res); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+}
+
+func Test_VerifierConfigAllowlist(t *testing.T) {
+	// an empty allowlist allows nothing, unlike no allowlist
+	v := nep413.NewVerifier(nep413.WithAccountMatcher(nep413.AccountMatcher{}))
+	data, err := json.Marshal(v.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg nep413.VerifierConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := nep413.NewVerifierFromConfig(cfg, nep413.Dependencies{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+	if _, err := rebuilt.Verify(msg, res); !errors.Is(err, nep413.ErrAccountNotAllowed) {
+		t.Fatalf("expected ErrAccountNotAllowed, got %v", err)
+	}
+
+	invalid := map[string]nep413.VerifierConfig{
+		"account pattern":       {AllowedAccounts: []string{"*"}},
+		"key type":              {AllowedKeyTypes: []string{"rsa"}},
+		"message normalization": {MessageNormalization: "NFX"},
+		"payload hashing":       {PayloadHashing: "md5"},
+	}
+	for name, cfg := range invalid {
+		if _, err := nep413.NewVerifierFromConfig(cfg, nep413.Dependencies{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
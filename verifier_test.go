@@ -0,0 +1,187 @@
+package nep413_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifierHooks(t *testing.T) {
+	var calls []string
+
+	v := nep413.NewVerifier()
+	v.OnBeforeVerify = func(ctx context.Context, msg *nep413.Nep413Message, res *nep413.Nep413SignatureResponse) {
+		calls = append(calls, "before")
+	}
+	v.OnAfterVerify = func(ctx context.Context, result *nep413.VerifyResult, err error) {
+		if err != nil {
+			calls = append(calls, "after:"+err.Error())
+			return
+		}
+		calls = append(calls, "after:"+result.AccountId)
+	}
+
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	msg.Message = "tampered"
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrVerificationFailed) {
+		t.Fatalf("expected ErrVerificationFailed, got %v", err)
+	}
+
+	expected := []string{"before", "after:idos.near", "before", "after:" + nep413.ErrVerificationFailed.Error()}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected calls %v, got %v", expected, calls)
+		}
+	}
+}
+
+func Test_VerifierStats(t *testing.T) {
+	v := nep413.NewVerifier(nep413.WithAllowedAccounts("idos.near"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			msg, res := testVector()
+			res.AccountId = "idos.near"
+			switch i % 4 {
+			case 0:
+				msg.Message = "tampered"
+			case 1:
+				res.AccountId = "other.near"
+			}
+
+			v.Verify(msg, res)
+		}(i)
+	}
+	wg.Wait()
+
+	stats := v.Stats.Snapshot()
+	if stats.Attempted != 20 || stats.Succeeded != 10 || stats.Failed != 10 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	if stats.FailedByReason["invalid_signature"] != 5 || stats.FailedByReason["account_not_allowed"] != 5 {
+		t.Fatalf("unexpected failure reasons %v", stats.FailedByReason)
+	}
+
+	// every verification is in the last bucket, and the counts are cumulative
+	last := stats.LatencyBuckets[len(stats.LatencyBuckets)-1]
+	if last != 20 || stats.LatencyCount != 20 || stats.LatencyBuckets[0] > last || stats.LatencySum <= 0 {
+		t.Fatalf("unexpected latencies %v (sum %v)", stats.LatencyBuckets, stats.LatencySum)
+	}
+}
+
+func Test_VerifyResult(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	result, err := nep413.NewVerifier().Verify(msg, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.AccountId != "idos.near" || result.NearKey != res.PublicKey || result.Recipient != msg.Recipient || result.Nonce != msg.Nonce {
+		t.Fatalf("unexpected result %+v", result)
+	}
+
+	if result.PayloadHash != sha256.Sum256(testPayload(t, msg)) {
+		t.Fatal("unexpected payload hash")
+	}
+
+	// the hash is the digest the signature is over
+	signature, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ed25519.Verify(result.PublicKey, result.PayloadHash[:], signature) {
+		t.Fatal("expected signature to verify against the payload hash")
+	}
+}
+
+func Test_VerifyContextCancelled(t *testing.T) {
+	store := nep413.NewMemoryNonceStore(time.Hour)
+	v := nep413.NewVerifier(nep413.WithNonceStore(store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	msg, res := testVector()
+	if _, err := v.VerifyContext(ctx, msg, res); !errors.Is(err, context.Canceled) || !errors.Is(err, nep413.ErrVerificationCancelled) {
+		t.Fatalf("expected ErrVerificationCancelled wrapping context.Canceled, got %v", err)
+	}
+
+	// the nonce was not consumed
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_VerifierNowFunc(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	msg, _ := testVector()
+	msg.Nonce = timestampedNonce(t, clock.Add(-time.Minute))
+	res, _ := testSign(t, msg)
+
+	v := nep413.NewVerifier(nep413.WithNonceFreshness(5*time.Minute, 0))
+
+	// against the real clock, the nonce is long expired
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrNonceExpired) {
+		t.Fatalf("expected ErrNonceExpired, got %v", err)
+	}
+
+	v.NowFunc = func() time.Time { return clock }
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+
+	v.NowFunc = func() time.Time { return clock.Add(-2 * time.Minute) }
+	if _, err := v.Verify(msg, res); !errors.Is(err, nep413.ErrNonceInFuture) {
+		t.Fatalf("expected ErrNonceInFuture, got %v", err)
+	}
+}
+
+func Test_ZeroValueVerifier(t *testing.T) {
+	msg, res := testVector()
+
+	var v nep413.Verifier
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Explain(context.Background(), msg, res); err != nil {
+		t.Fatal(err)
+	}
+	v.InvalidateAccount("idos.near")
+	if err := v.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// setting a recipient on one zero value verifier does not change the defaults of others
+	var other nep413.Verifier
+	other.WithExpectedRecipient("other.app")
+	if _, err := other.Verify(msg, res); !errors.Is(err, nep413.ErrRecipientMismatch) {
+		t.Fatalf("expected ErrRecipientMismatch, got %v", err)
+	}
+	if _, err := v.Verify(msg, res); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package nep413
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrMalformedResponse is returned when a wallet response is not valid JSON.
+var ErrMalformedResponse = errors.New("malformed wallet response")
+
+// VerifyJSON verifies a wallet's raw JSON response, e.g. {"accountId", "publicKey", "signature"},
+// against msg, with the given options.
+// JSON that cannot be decoded fails with an error wrapping ErrMalformedResponse, and a
+// response without a signature or public key with one wrapping ErrMissingField.
+func VerifyJSON(msg *Nep413Message, responseJSON []byte, opts ...VerifyOption) (*VerifyResult, error) {
+	return NewVerifier(opts...).VerifyJSON(msg, responseJSON)
+}
+
+// VerifyJSON verifies a wallet's raw JSON response against msg, with the verifier's full
+// policy. See the VerifyJSON function for the errors returned for malformed responses.
+func (v *Verifier) VerifyJSON(msg *Nep413Message, responseJSON []byte) (*VerifyResult, error) {
+	res, err := parseResponseJSON(responseJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return v.VerifyContext(context.Background(), msg, res)
+}
+
+// parseResponseJSON decodes a wallet's JSON response, checking its required fields.
+func parseResponseJSON(data []byte) (*Nep413SignatureResponse, error) {
+	var res Nep413SignatureResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	}
+
+	if res.Signature == "" {
+		return nil, fmt.Errorf("%w: signature", ErrMissingField)
+	}
+	if res.PublicKey == "" {
+		return nil, fmt.Errorf("%w: publicKey", ErrMissingField)
+	}
+
+	return &res, nil
+}
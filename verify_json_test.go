@@ -0,0 +1,41 @@
+package nep413_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+)
+
+func Test_VerifyJSON(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := nep413.VerifyJSON(msg, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.AccountId != "idos.near" {
+		t.Fatalf("expected account id idos.near, got %s", result.AccountId)
+	}
+
+	// the verifier's policy applies
+	v := nep413.NewVerifier(nep413.WithAllowedAccounts("other.near"))
+	if _, err := v.VerifyJSON(msg, data); !errors.Is(err, nep413.ErrAccountNotAllowed) {
+		t.Fatalf("expected ErrAccountNotAllowed, got %v", err)
+	}
+
+	if _, err := nep413.VerifyJSON(msg, []byte(`{"signature": `)); !errors.Is(err, nep413.ErrMalformedResponse) {
+		t.Fatalf("expected ErrMalformedResponse, got %v", err)
+	}
+
+	if _, err := nep413.VerifyJSON(msg, []byte(`{"signature": "abc"}`)); !errors.Is(err, nep413.ErrMissingField) {
+		t.Fatalf("expected ErrMissingField, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package nep413
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/mr-tron/base58"
+)
+
+// WalletShape identifies the shape of a wallet's signMessage result.
+type WalletShape string
+
+const (
+	// WalletShapeWalletSelector is wallet-selector's SignedMessage, {accountId, publicKey,
+	// signature, state}, either as is, or in the envelope parsed by ParseSignedMessageEnvelope.
+	WalletShapeWalletSelector WalletShape = "wallet-selector"
+	// WalletShapeNearApiJs is a near-api-js Signature object passed through JSON.stringify:
+	// {signature, publicKey: {keyType, data}, accountId}, with the bytes as arrays or as
+	// objects keyed by index, as JSON.stringify encodes a Uint8Array.
+	WalletShapeNearApiJs WalletShape = "near-api-js"
+	// WalletShapeMeteor is the result as wrapped by Meteor's wallet bridge,
+	// {success, payload: {accountId, publicKey, signature}}.
+	WalletShapeMeteor WalletShape = "meteor"
+	// WalletShapeHere is HERE wallet's result, which echoes the signed fields next to the
+	// signature: {accountId, publicKey, signature, message, nonce, recipient}.
+	WalletShapeHere WalletShape = "here"
+)
+
+// NormalizeWalletResponse parses a wallet's signMessage result in any of the known shapes,
+// so that integrators need not know which wallet produced it. See DetectWalletResponse.
+func NormalizeWalletResponse(data []byte) (*Nep413SignatureResponse, error) {
+	res, _, err := DetectWalletResponse(data)
+	return res, err
+}
+
+// DetectWalletResponse parses a wallet's signMessage result, trying each shape's parser in
+// turn, and returns the first complete response, along with the shape that matched, e.g. for
+// logging. The shapes are tried from the most to the least specific: the wallet-selector
+// envelope, near-api-js, Meteor, HERE, and finally wallet-selector's plain SignedMessage,
+// which the other shapes' fields would otherwise hide behind. If no shape matches, the error
+// wraps ErrMalformedResponse, along with each parser's error.
+func DetectWalletResponse(data []byte) (*Nep413SignatureResponse, WalletShape, error) {
+	if !json.Valid(data) {
+		return nil, "", fmt.Errorf("%w: invalid JSON", ErrMalformedResponse)
+	}
+
+	parsers := []struct {
+		shape WalletShape
+		parse func([]byte) (*Nep413SignatureResponse, error)
+	}{
+		{WalletShapeWalletSelector, parseWalletSelectorEnvelope},
+		{WalletShapeNearApiJs, ParseNearApiJsResponse},
+		{WalletShapeMeteor, ParseMeteorResponse},
+		{WalletShapeHere, ParseHereResponse},
+		{WalletShapeWalletSelector, parseResponseJSON},
+	}
+
+	var errs []error
+	for _, parser := range parsers {
+		res, err := parser.parse(data)
+		if err == nil {
+			return res, parser.shape, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", parser.shape, err))
+	}
+
+	return nil, "", fmt.Errorf("%w: no known wallet response shape matched: %w", ErrMalformedResponse, errors.Join(errs...))
+}
+
+// ParseWalletSelectorResponse parses wallet-selector's SignedMessage, either as is, or in
+// the envelope parsed by ParseSignedMessageEnvelope.
+func ParseWalletSelectorResponse(data []byte) (*Nep413SignatureResponse, error) {
+	if res, err := parseWalletSelectorEnvelope(data); err == nil {
+		return res, nil
+	}
+
+	return parseResponseJSON(data)
+}
+
+// parseWalletSelectorEnvelope parses the response from a wallet-selector envelope.
+func parseWalletSelectorEnvelope(data []byte) (*Nep413SignatureResponse, error) {
+	_, res, err := ParseSignedMessageEnvelope(data)
+	return res, err
+}
+
+// nearApiJsResponse is a near-api-js Signature object, encoded by JSON.stringify.
+type nearApiJsResponse struct {
+	Signature json.RawMessage `json:"signature"`
+	PublicKey json.RawMessage `json:"publicKey"`
+	AccountId string          `json:"accountId"`
+	State     string          `json:"state"`
+}
+
+// ParseNearApiJsResponse parses a near-api-js Signature object passed through JSON.stringify,
+// whose public key is a {keyType, data} object rather than a string. The bytes may be arrays,
+// objects keyed by index, or base64 strings.
+func ParseNearApiJsResponse(data []byte) (*Nep413SignatureResponse, error) {
+	var decoded nearApiJsResponse
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	}
+
+	if len(decoded.Signature) == 0 || string(decoded.Signature) == "null" {
+		return nil, fmt.Errorf("%w: signature", ErrMissingField)
+	}
+	if len(decoded.PublicKey) == 0 || string(decoded.PublicKey) == "null" {
+		return nil, fmt.Errorf("%w: publicKey", ErrMissingField)
+	}
+
+	signature, err := decodeJSONBytes(decoded.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	publicKey, err := decodeNearApiJsPublicKey(decoded.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Nep413SignatureResponse{
+		Signature: base64.StdEncoding.EncodeToString(signature),
+		PublicKey: publicKey,
+		AccountId: decoded.AccountId,
+		State:     decoded.State,
+	}, nil
+}
+
+// decodeNearApiJsPublicKey decodes a near-api-js PublicKey object, {keyType, data}, into
+// NEAR's string format. keyType is 0 for ed25519 and 1 for secp256k1, as in near-api-js.
+func decodeNearApiJsPublicKey(raw json.RawMessage) (string, error) {
+	var key struct {
+		KeyType *int            `json:"keyType"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &key); err != nil || key.KeyType == nil || len(key.Data) == 0 {
+		return "", errors.New("invalid public key, expected a {keyType, data} object")
+	}
+
+	keyBytes, err := decodeJSONBytes(key.Data)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key: %w", err)
+	}
+
+	var keyType KeyType
+	switch *key.KeyType {
+	case 0:
+		keyType = KeyTypeED25519
+	case 1:
+		keyType = KeyTypeSECP256K1
+	default:
+		return "", fmt.Errorf("%w: near-api-js key type %d", ErrUnsupportedKeyType, *key.KeyType)
+	}
+
+	return keyType.String() + ":" + base58.EncodeAlphabet(keyBytes, nearAlphabet), nil
+}
+
+// decodeJSONBytes decodes bytes encoded as a JSON array, as an object keyed by index (as
+// JSON.stringify encodes a Uint8Array), or as a base64 string.
+func decodeJSONBytes(raw json.RawMessage) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return base64.StdEncoding.DecodeString(s)
+	}
+
+	var array []byte
+	if err := json.Unmarshal(raw, &array); err == nil {
+		return array, nil
+	}
+
+	var indexed map[string]byte
+	if err := json.Unmarshal(raw, &indexed); err != nil {
+		return nil, errors.New("expected a byte array, an object keyed by index, or a base64 string")
+	}
+
+	decoded := make([]byte, len(indexed))
+	for i := range decoded {
+		b, ok := indexed[strconv.Itoa(i)]
+		if !ok {
+			return nil, fmt.Errorf("missing byte %d", i)
+		}
+		decoded[i] = b
+	}
+
+	return decoded, nil
+}
+
+// meteorResponse is a result wrapped by Meteor's wallet bridge.
+type meteorResponse struct {
+	Success *bool           `json:"success"`
+	Payload json.RawMessage `json:"payload"`
+	Message string          `json:"message"`
+}
+
+// ParseMeteorResponse parses a result wrapped by Meteor's wallet bridge, {success, payload}.
+// An unsuccessful result fails with the wallet's message.
+func ParseMeteorResponse(data []byte) (*Nep413SignatureResponse, error) {
+	var decoded meteorResponse
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	}
+
+	if decoded.Success == nil {
+		return nil, fmt.Errorf("%w: success", ErrMissingField)
+	}
+	if !*decoded.Success {
+		return nil, fmt.Errorf("wallet reported failure: %s", decoded.Message)
+	}
+	if len(decoded.Payload) == 0 || string(decoded.Payload) == "null" {
+		return nil, fmt.Errorf("%w: payload", ErrMissingField)
+	}
+
+	return parseResponseJSON(decoded.Payload)
+}
+
+// ParseHereResponse parses HERE wallet's result, which echoes the signed nonce and recipient
+// next to the signature. Only the response is returned; the echoed fields are unauthenticated,
+// so the message must still be the one the server issued.
+func ParseHereResponse(data []byte) (*Nep413SignatureResponse, error) {
+	var echoed struct {
+		Nonce     json.RawMessage `json:"nonce"`
+		Recipient string          `json:"recipient"`
+	}
+	if err := json.Unmarshal(data, &echoed); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedResponse, err)
+	}
+
+	if len(echoed.Nonce) == 0 || string(echoed.Nonce) == "null" {
+		return nil, fmt.Errorf("%w: nonce", ErrMissingField)
+	}
+	if echoed.Recipient == "" {
+		return nil, fmt.Errorf("%w: recipient", ErrMissingField)
+	}
+
+	return parseResponseJSON(data)
+}
@@ -0,0 +1,93 @@
+package nep413_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+)
+
+func Test_DetectWalletResponse(t *testing.T) {
+	msg, res := testVector()
+	res.AccountId = "idos.near"
+
+	flat, err := json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(res.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := base58.Decode(strings.TrimPrefix(res.PublicKey, "ed25519:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// JSON.stringify encodes a Uint8Array as an object keyed by index
+	indexed := make(map[string]byte, len(keyBytes))
+	for i, b := range keyBytes {
+		indexed[fmt.Sprint(i)] = b
+	}
+	nearApiJs, err := json.Marshal(map[string]any{
+		"signature": json.RawMessage(arrayJSON(signature)),
+		"publicKey": map[string]any{"keyType": 0, "data": indexed},
+		"accountId": res.AccountId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		data  string
+		shape nep413.WalletShape
+	}{
+		{"flat", string(flat), nep413.WalletShapeWalletSelector},
+		{"envelope", `{"signedMessage":` + string(flat) + `,"nonce":` + arrayJSON(msg.Nonce[:]) + `,"recipient":"idos.network","message":"idOS authentication"}`, nep413.WalletShapeWalletSelector},
+		{"near-api-js", string(nearApiJs), nep413.WalletShapeNearApiJs},
+		{"meteor", `{"success":true,"payload":` + string(flat) + `}`, nep413.WalletShapeMeteor},
+		{"here", strings.TrimSuffix(string(flat), "}") + `,"nonce":` + arrayJSON(msg.Nonce[:]) + `,"recipient":"idos.network"}`, nep413.WalletShapeHere},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, shape, err := nep413.DetectWalletResponse([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if shape != tt.shape {
+				t.Fatalf("expected shape %s, got %s", tt.shape, shape)
+			}
+			if *normalized != *res {
+				t.Fatalf("expected %+v, got %+v", res, normalized)
+			}
+			if err := nep413.Verify(msg, normalized); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	for _, data := range []string{`{"accountId":"idos.near"}`, `{"success":false,"message":"user rejected"}`, `not json`} {
+		if _, err := nep413.NormalizeWalletResponse([]byte(data)); !errors.Is(err, nep413.ErrMalformedResponse) {
+			t.Fatalf("expected ErrMalformedResponse for %s, got %v", data, err)
+		}
+	}
+}
+
+// arrayJSON encodes bytes as a JSON array of numbers, as JavaScript's Array.from does.
+func arrayJSON(b []byte) string {
+	ints := make([]int, len(b))
+	for i := range b {
+		ints[i] = int(b[i])
+	}
+
+	data, _ := json.Marshal(ints)
+	return string(data)
+}
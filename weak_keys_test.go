@@ -0,0 +1,48 @@
+package nep413_test
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/brennanjl/nep413"
+	"github.com/mr-tron/base58"
+)
+
+func Test_RejectWeakPublicKey(t *testing.T) {
+	msg, _ := testVector()
+
+	// with the identity point as the public key, (R, S) = ([1]B, 1) is a valid
+	// signature for every message
+	identity := make(ed25519.PublicKey, ed25519.PublicKeySize)
+	identity[0] = 1
+
+	scalarOne := make([]byte, 32)
+	scalarOne[0] = 1
+	signature := append(basePointBytes(), scalarOne...)
+
+	res := &nep413.Nep413SignatureResponse{
+		Signature: base64.StdEncoding.EncodeToString(signature),
+		PublicKey: "ed25519:" + base58.Encode(identity),
+	}
+
+	hash := sha256.Sum256(testPayload(t, msg))
+	if !ed25519.Verify(identity, hash[:], signature) {
+		t.Skip("ed25519 implementation rejects small-order keys")
+	}
+
+	if err := nep413.Verify(msg, res); err != nil {
+		t.Fatalf("expected forged signature to pass lenient verification, got %v", err)
+	}
+
+	if err := nep413.Verify(msg, res, nep413.VerifyStrict()); !errors.Is(err, nep413.ErrWeakPublicKey) {
+		t.Fatalf("expected ErrWeakPublicKey, got %v", err)
+	}
+}
+
+// basePointBytes returns the encoding of the edwards25519 base point.
+func basePointBytes() []byte {
+	return []byte{0x58, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66, 0x66}
+}